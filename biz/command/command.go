@@ -0,0 +1,23 @@
+package command
+
+import (
+	"fmt"
+)
+
+type Command func(args []string) error
+
+var commands = map[string]Command{
+	"index": Index,
+}
+
+// Run 执行子命令，args[0] 为子命令名称
+func Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return cmd(args[1:])
+}
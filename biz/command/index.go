@@ -0,0 +1,76 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/flags"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+)
+
+const indexUsage = "usage: index export|import -workspace <dir> <file> [module...]"
+
+// Index 处理 index export|import -workspace <dir> <file> [module...]。
+// 索引中记录的是打开的工作区目录，和命令的当前目录无关，所以需要明确指定
+func Index(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(indexUsage)
+	}
+	set := flag.NewFlagSet("index "+args[0], flag.ContinueOnError)
+	workspace := set.String("workspace", "", "工作区目录，导出时替换为 "+cache.WorkspaceRoot+"，导入时替换回来")
+	err := set.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if *workspace == "" || set.NArg() < 1 {
+		return fmt.Errorf(indexUsage)
+	}
+	root, err := filepath.Abs(*workspace)
+	if err != nil {
+		return err
+	}
+	roots := cache.TransferRoots{
+		ModCache:  flags.GetGoModCache(),
+		Workspace: root,
+	}
+
+	switch args[0] {
+	case "export":
+		return indexExport(set.Arg(0), set.Args()[1:], roots)
+	case "import":
+		return indexImport(set.Arg(0), roots)
+	}
+	return fmt.Errorf("unknown index command: %s", args[0])
+}
+
+func indexExport(filename string, modules []string, roots cache.TransferRoots) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = cache.ExportIndex(f, modules, roots)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("file", filename).Strs("modules", modules).Msg("export index ok")
+	return nil
+}
+
+func indexImport(filename string, roots cache.TransferRoots) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = cache.ImportIndex(f, roots)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("file", filename).Msg("import index ok")
+	return nil
+}
@@ -11,15 +11,20 @@ const (
 
 // 设置lsp.Server默认功能全部关闭
 var ServerCapabilities = lsp.ServerCapabilities{
+	TextDocumentSync: lsp.TextDocumentSyncOptions{
+		OpenClose: true,
+		Change:    lsp.TDSyncKindFull,
+	},
 	HoverProvider: &lsp.HoverOptions{
 		WorkDoneProgressOptions: lsp.WorkDoneProgressOptions{
 			WorkDoneProgress: true,
 		},
 	},
+	DefinitionProvider: &lsp.DefinitionOptions{},
 	CompletionProvider: &lsp.CompletionOptions{
 		ResolveProvider: true,
 		TriggerCharacters: []string{
-			"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", ".",
+			"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z", ".", "/", "\"",
 		},
 	},
 }
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/token"
+	"strings"
+	"time"
+)
+
+// FileIndexes 将文件中包级别的符号转换为索引
+func FileIndexes(gf *file.GoFile, pkg *model.Package) []model.Index {
+	var indexes []model.Index
+	now := time.Now()
+	var build string
+	if gf.Constraint != nil {
+		build = gf.Constraint.String()
+	}
+	newIndex := func(name string, typ int32, pos token.Pos) model.Index {
+		position := gf.FileSet.Position(pos)
+		return model.Index{
+			KeyWorld:   name,
+			Type:       typ,
+			FilePath:   position.Filename,
+			Package:    gf.File.Name.Name,
+			JoinLine:   position.Line,
+			JoinCol:    position.Column,
+			PackageID:  int32(pkg.ID),
+			Workspace:  pkg.Workspace,
+			Build:      build,
+			UpdateTime: now,
+		}
+	}
+
+	const global = "global"
+	for name, v := range gf.Variables[global] {
+		index := newIndex(name, model.IndexTypeVar, v.Scope.Start)
+		index.Extra = v.Type
+		indexes = append(indexes, index)
+	}
+	for name, fn := range gf.Functions[global] {
+		index := newIndex(name, model.IndexTypeFunc, fn.Scope.Start)
+		index.Extra = fn.Type
+		indexes = append(indexes, index)
+	}
+	for name, t := range gf.Types[global] {
+		index := newIndex(name, model.IndexTypeType, t.Scope.Start)
+		// 包括类型参数和约束，比如 Number interface{~int | ~float64}
+		index.Extra = t.Decl()
+		indexes = append(indexes, index)
+
+		for _, field := range t.Fields {
+			index := newIndex(*field.Name, model.IndexTypeField, field.Scope.Start)
+			index.Comparable = name
+			index.Extra = field.Type
+			index.Tags = indexTags(field.Tags)
+			indexes = append(indexes, index)
+		}
+		for _, method := range t.Methods {
+			index := newIndex(method.Name, model.IndexTypeMethod, method.Scope.Start)
+			index.Comparable = name
+			index.Extra = method.Type
+			indexes = append(indexes, index)
+		}
+		for _, embed := range t.Embeds {
+			index := newIndex(embed.Name, model.IndexTypeEmbed, t.Scope.Start)
+			index.Comparable = name
+			index.Extra = embed.Type
+			indexes = append(indexes, index)
+		}
+	}
+	for recv, methods := range gf.Methods {
+		for name, fn := range methods {
+			index := newIndex(name, model.IndexTypeMethod, fn.Scope.Start)
+			index.Comparable = recv
+			index.Extra = MethodExtra(fn)
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// IndexString 返回索引符号的 go 语法描述
+func IndexString(index *model.Index) string {
+	switch index.Type {
+	case model.IndexTypeVar:
+		return "var " + index.Package + "." + index.KeyWorld + " " + index.Extra
+	case model.IndexTypeFunc:
+		return "func " + index.Package + "." + index.KeyWorld + strings.TrimPrefix(index.Extra, "func")
+	case model.IndexTypeType:
+		return "type " + index.Package + "." + index.Extra
+	case model.IndexTypeMethod:
+		typ, pointer, ok := ParseMethodExtra(index.Extra)
+		recv := index.Comparable
+		if ok && pointer {
+			recv = "*" + recv
+		}
+		return "func (" + recv + ") " + index.KeyWorld + strings.TrimPrefix(typ, "func")
+	case model.IndexTypeField:
+		if len(index.Tags) == 0 {
+			return "field " + index.KeyWorld + " " + index.Extra
+		}
+		return "field " + index.KeyWorld + " " + index.Extra + " `" + file.FormatTag(FieldTags(index)) + "`"
+	case model.IndexTypeEmbed:
+		return "field " + index.KeyWorld + " " + index.Extra
+	}
+	return index.KeyWorld
+}
+
+// indexTags 字段标签转换为索引中保存的键值对
+func indexTags(tags []file.TagSpec) []model.Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+	out := make([]model.Tag, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, model.Tag{Key: t.Key, Value: t.Value})
+	}
+	return out
+}
+
+// FieldTags 字段索引保存的标签
+func FieldTags(index *model.Index) []file.TagSpec {
+	if len(index.Tags) == 0 {
+		return nil
+	}
+	tags := make([]file.TagSpec, 0, len(index.Tags))
+	for _, t := range index.Tags {
+		tags = append(tags, file.TagSpec{Key: t.Key, Value: t.Value})
+	}
+	return tags
+}
+
+// MethodExtra 方法索引的 Extra 列，函数签名前面是接收者，比如 (*T) func() error，
+// 接口声明的方法没有接收者，只有函数签名
+func MethodExtra(fn file.FuncSpec) string {
+	if fn.Recv == nil {
+		return fn.Type
+	}
+	if fn.Recv.Pointer {
+		return "(*" + fn.Recv.Type + ") " + fn.Type
+	}
+	return "(" + fn.Recv.Type + ") " + fn.Type
+}
+
+// ParseMethodExtra 从方法索引的 Extra 列中拆出函数签名和是否为指针接收者，没有接收者时 ok 为 false
+func ParseMethodExtra(extra string) (typ string, pointer bool, ok bool) {
+	if !strings.HasPrefix(extra, "(") {
+		return extra, false, false
+	}
+	recv, typ, ok := strings.Cut(extra[1:], ") ")
+	if !ok {
+		return extra, false, false
+	}
+	return typ, strings.HasPrefix(recv, "*"), true
+}
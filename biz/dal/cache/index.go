@@ -2,6 +2,9 @@ package cache
 
 import (
 	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"gorm.io/gorm"
+	"path/filepath"
 )
 
 func QueryIndexByPackageID(PackageID int) ([]*model.Index, error) {
@@ -20,10 +23,16 @@ func QueryIndexByPackageID(PackageID int) ([]*model.Index, error) {
 }
 
 type IndexFindParams struct {
-	Keyword   *string
-	Filename  *string
-	PackageID *int32
-	Type      *int32
+	Keyword     *string
+	Filename    *string
+	PackageID   *int32
+	Package     *string // 包名，不同路径的同名包会混在一起，优先使用 PackagePath
+	PackagePath *string // 包的 import 路径
+	Dir         *string // 只返回该目录中的文件的索引，也就是同一个包的索引
+	Comparable  *string // 字段和方法所属的类型
+	Type        *int32
+	Workspace   *string           // 只返回该工作区和共享包(依赖、标准库)的索引
+	Build       *file.BuildConfig // 只返回构建约束在该配置下成立的索引
 }
 
 func FindIndex(params IndexFindParams) ([]*model.Index, error) {
@@ -37,23 +46,68 @@ func FindIndex(params IndexFindParams) ([]*model.Index, error) {
 	}
 
 	if params.Filename != nil {
-		db = db.Where("filename = ?", *params.Filename)
+		db = db.Where("file_path = ?", *params.Filename)
 	}
 	if params.Type != nil {
 		db = db.Where("type = ?", *params.Type)
 	}
 
 	if params.Keyword != nil {
-		db = db.Where("keyword = ?", *params.Keyword)
+		db = db.Where("key_world = ?", *params.Keyword)
+	}
+	if params.Package != nil {
+		db = db.Where("package = ?", *params.Package)
+	}
+	if params.PackagePath != nil {
+		db = db.Where("package_id in (?)", packageIDs(*params.PackagePath))
+	}
+	if params.Dir != nil {
+		db = db.Where(`file_path like ? escape '\'`, likePrefix(*params.Dir+string(filepath.Separator)))
+	}
+	if params.Comparable != nil {
+		db = db.Where("comparable = ?", *params.Comparable)
+	}
+
+	if params.Workspace != nil {
+		db = db.Where("workspace = ? or workspace = ''", *params.Workspace)
 	}
 	var results []*model.Index
 	err = db.Find(&results).Error
 	if err != nil {
 		return nil, err
 	}
+	if params.Build != nil {
+		results = FilterBuild(results, *params.Build)
+	}
+	if params.Dir != nil {
+		// like 也会匹配子目录中的文件
+		results = filterDir(results, *params.Dir)
+	}
 	return results, nil
 }
 
+// FilterBuild 去掉构建约束在该配置下不成立的索引，比如 GOOS=linux 时的 foo_windows.go
+func FilterBuild(indexes []*model.Index, build file.BuildConfig) []*model.Index {
+	results := indexes[:0]
+	for _, index := range indexes {
+		if build.MatchString(index.Build) {
+			results = append(results, index)
+		}
+	}
+	return results
+}
+
+// filterDir 只保留 dir 目录中的文件的索引
+func filterDir(indexes []*model.Index, dir string) []*model.Index {
+	results := indexes[:0]
+	for _, index := range indexes {
+		if filepath.Dir(index.FilePath) == dir {
+			results = append(results, index)
+		}
+	}
+	return results
+}
+
 func CreateIndex(index model.Index) error {
 	db := DB.Table(model.IndexTableName)
 	err := db.AutoMigrate()
@@ -62,3 +116,27 @@ func CreateIndex(index model.Index) error {
 	}
 	return db.Create(&index).Error
 }
+
+// DeleteIndexByPackageID 删除包的全部索引
+func DeleteIndexByPackageID(packageID int32) error {
+	db := DB.Table(model.IndexTableName)
+	return db.Where("package_id = ?", packageID).Delete(&model.Index{}).Error
+}
+
+// FindMethods 查找 import 路径为 path 的包中类型 recv 的全部方法，包括接口声明的方法，只返回该工作区和共享包的索引
+func FindMethods(path, recv string, workspace string) ([]*model.Index, error) {
+	db := DB.Table(model.IndexTableName)
+	var results []*model.Index
+	err := db.Where("workspace = ? or workspace = ''", workspace).
+		Where("package_id in (?) and type = ? and comparable = ?", packageIDs(path), model.IndexTypeMethod, recv).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// packageIDs import 路径为 path 的包的 id，用作子查询。不同工作区中同一个路径的包由索引的 workspace 区分
+func packageIDs(path string) *gorm.DB {
+	return DB.Table(model.PackageTableName).Select("id").Where("name = ?", path)
+}
@@ -1,25 +1,121 @@
 package cache
 
 import (
+	"fmt"
 	"github.com/denstiny/golang-language-server/biz/conts"
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
 	"github.com/denstiny/golang-language-server/biz/flags"
+	"github.com/rs/zerolog/log"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"os"
 	"path"
+	"time"
 )
 
 var (
 	DB *gorm.DB
 )
 
-func init() {
+// Init 打开配置目录中的缓存数据库，需要在 flags.Parse 之后调用
+func Init() {
 	dbpath := path.Join(flags.SERVICE_CONFIG_DIR, conts.CacheFileName)
+	db, err := openDB(dbpath)
+	if err != nil {
+		// 进程在索引过程中被杀掉可能导致数据库损坏，隔离旧文件后重建
+		log.Error().Str("path", dbpath).Msg("cache database is corrupt, rebuild it: " + err.Error())
+		if err = quarantineDB(dbpath); err != nil {
+			panic("failed to quarantine database: " + err.Error())
+		}
+		db, err = openDB(dbpath)
+		if err != nil {
+			panic("failed to connect database")
+		}
+	}
+	DB = db
+
+	err = migrate(db)
+	if err != nil {
+		panic("failed to migrate database: " + err.Error())
+	}
+
+	// 未完成索引的包需要清理残留的索引，等待重新索引
+	err = DeletePartialPackages()
+	if err != nil {
+		log.Error().Msg("delete partial packages failed: " + err.Error())
+	}
+}
+
+// openDB 打开数据库并做完整性检查
+func openDB(dbpath string) (*gorm.DB, error) {
 	db, err := gorm.Open(sqlite.Open(dbpath), &gorm.Config{
 		SkipDefaultTransaction: false,
 		PrepareStmt:            true,
 	})
 	if err != nil {
-		panic("failed to connect database")
+		return nil, err
 	}
-	DB = db
+
+	err = checkIntegrity(db)
+	if err != nil {
+		closeDB(db)
+		return nil, err
+	}
+
+	// WAL 模式下写入中断不会破坏已提交的数据
+	var mode string
+	err = db.Raw("PRAGMA journal_mode=WAL").Scan(&mode).Error
+	if err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	return db, nil
+}
+
+func migrate(db *gorm.DB) error {
+	err := db.Table(model.PackageTableName).AutoMigrate(&model.Package{})
+	if err != nil {
+		return err
+	}
+	err = db.Table(model.IndexTableName).AutoMigrate(&model.Index{})
+	if err != nil {
+		return err
+	}
+	return db.Table(model.PackageLibranyTablName).AutoMigrate(&model.PackageLibrany{})
+}
+
+func checkIntegrity(db *gorm.DB) error {
+	var results []string
+	err := db.Raw("PRAGMA quick_check").Scan(&results).Error
+	if err != nil {
+		return err
+	}
+	if len(results) != 1 || results[0] != "ok" {
+		return fmt.Errorf("quick_check failed: %v", results)
+	}
+	return nil
+}
+
+func closeDB(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	sqlDB.Close()
+}
+
+// quarantineDB 将损坏的数据库文件(包括 wal/shm)重命名为 *.corrupt-<时间>
+func quarantineDB(dbpath string) error {
+	suffix := ".corrupt-" + time.Now().Format("20060102150405")
+	for _, ext := range []string{"", "-wal", "-shm"} {
+		p := dbpath + ext
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			continue
+		}
+		err := os.Rename(p, p+suffix)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"path/filepath"
+	"testing"
+)
+
+// 标签中缺少分号时 gorm 把 index 当作类型的一部分，索引不会被创建，type:int:index 甚至不能建表
+func TestMigrateCreatesIndexes(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "migrate.db"))
+	tables := map[string][]string{
+		model.PackageTableName:       {"idx_name", "idx_package_workspace"},
+		model.IndexTableName:         {"idx_key_world", "idx_type", "idx_file_path", "idx_package", "idx_package_id", "idx_workspace"},
+		model.PackageLibranyTablName: {"idx_parent_id"},
+	}
+	for table, want := range tables {
+		var names []string
+		err := DB.Raw("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ?", table).Scan(&names).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		created := make(map[string]bool, len(names))
+		for _, name := range names {
+			created[name] = true
+		}
+		for _, name := range want {
+			if !created[name] {
+				t.Errorf("table %s: index %s not created, got %v", table, name, names)
+			}
+		}
+	}
+}
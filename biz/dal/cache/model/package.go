@@ -19,12 +19,17 @@ Package 结构体用于表示一个软件包的相关信息，这些信息会被
     数据库类型为 varchar(1024)，同时创建了名为 idx_repo 的索引，方便对仓库地址相关的查询操作。
   - Version: 软件包的版本号，用于区分同一软件包的不同迭代版本。数据库字段名为 "version"，JSON 键名为 "version"。
     数据库类型为 varchar(1024)，并创建了名为 idx_version 的索引，有助于提高基于版本号的查询效率。
+  - IndexDone: 包的索引是否已经全部写入。索引过程中进程被杀掉时该标记为 false，启动时会清理残留索引并重新索引。
+  - Workspace: 包所属的工作区(module 根目录)。依赖包和标准库为空，所有工作区共享；
+    同一仓库的不同 checkout 各自拥有一份包记录，互不影响。
 */
 type Package struct {
 	ID          int64  `db:"id" json:"id" gorm:"primary_key"`
-	Name        string `db:"name" json:"name" gorm:"not null;type:varchar(1024)index:idx_name"`
+	Name        string `db:"name" json:"name" gorm:"not null;type:varchar(1024);index:idx_name"`
 	PackageName string `db:"package_name" json:"package_name" gorm:"type:varchar(64)"`
 	Version     string `db:"version" json:"version" gorm:"type:varchar(1024)"`
+	IndexDone   bool   `db:"index_done" json:"index_done" gorm:"type:bool;default:false"`
+	Workspace   string `db:"workspace" json:"workspace" gorm:"type:varchar(2048);index:idx_package_workspace"`
 }
 
 // 存储包的依赖关系
@@ -46,22 +51,44 @@ Index 结构体代表了索引的相关信息，它会存储在数据库里，
   - Package: 索引所在的包名，明确索引所属的 Go 包，在数据库中对应 "package" 字段，JSON 序列化时键名为 "package"。
   - JoinLine: 索引所在的行号，精确到文件中的行位置，在数据库中对应 "join_line" 字段，JSON 序列化时键名为 "join_line"。
   - JoinCol: 索引所在的列号，精确到文件中的列位置，在数据库中对应 "join_col" 字段，JSON 序列化时键名为 "join_col"。
+  - Workspace: 索引所属的工作区(module 根目录)，为空时表示依赖包或标准库的索引，对所有工作区可见。
+  - Build: 符号所在文件的构建约束，比如 "linux && amd64"，为空时表示所有平台都可用。
+  - Tags: 结构体字段解析后的标签，按 json 保存在 "tags" 字段中，其他索引为空。
 */
 type Index struct {
 	ID         int       `db:"id" json:"id" gorm:"primary_key"`
 	Comparable string    `db:"comparable" json:"comparable" gorm:"type:text"`
-	KeyWorld   string    `db:"key_world" json:"key_world" gorm:"type:varchar(1024)index:idx_key_world"`
-	Type       int32     `db:"type" json:"type" gorm:"type:int:index:idx_type"`
+	KeyWorld   string    `db:"key_world" json:"key_world" gorm:"type:varchar(1024);index:idx_key_world"`
+	Type       int32     `db:"type" json:"type" gorm:"type:int;index:idx_type"`
 	JoinIndex  string    `db:"join_index" json:"join_index"`
-	FilePath   string    `db:"file_path" json:"file_path" gorm:"type:varchar(2048)index:idx_file_path"`
-	Package    string    `db:"package" json:"package" gorm:"type:varchar(1024)index:idx_package"`
+	FilePath   string    `db:"file_path" json:"file_path" gorm:"type:varchar(2048);index:idx_file_path"`
+	Package    string    `db:"package" json:"package" gorm:"type:varchar(1024);index:idx_package"`
 	JoinLine   int       `db:"join_line" json:"join_line" gorm:"type:int"`
-	JoinCol    int       `db:"join_col" json:"join_col" gorm:"type:"`
-	PackageID  int32     `db:"package_id" json:"package_id" gorm:"type:int index:idx_package_id"`
+	JoinCol    int       `db:"join_col" json:"join_col" gorm:"type:int"`
+	PackageID  int32     `db:"package_id" json:"package_id" gorm:"type:int;index:idx_package_id"`
 	Extra      string    `db:"extra" json:"extra" gorm:"type:text"`
+	Workspace  string    `db:"workspace" json:"workspace" gorm:"type:varchar(2048);index:idx_workspace"`
+	Build      string    `db:"build" json:"build" gorm:"type:text"`
+	Tags       []Tag     `db:"tags" json:"tags,omitempty" gorm:"type:text;serializer:json"`
 	UpdateTime time.Time `db:"update_time" json:"update_time" gorm:"type:datetime"`
 }
 
+// Tag 结构体字段标签中的一个键值对，比如 json:"name,omitempty" 的 Key 为 json，Value 为 name,omitempty
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Index.Type 的取值
+const (
+	IndexTypeVar    int32 = 1
+	IndexTypeFunc   int32 = 2
+	IndexTypeType   int32 = 3
+	IndexTypeMethod int32 = 4 // Comparable 为接收者类型或者接口，用于计算类型的方法集
+	IndexTypeField  int32 = 5 // Comparable 为字段所属的 struct
+	IndexTypeEmbed  int32 = 6 // Comparable 为嵌入所属的类型，Extra 为嵌入的完整类型
+)
+
 func (p *Package) IndexName() string {
 	return strings.Join([]string{p.Name, p.Version}, "@")
 }
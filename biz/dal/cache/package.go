@@ -1,6 +1,14 @@
 package cache
 
-import "github.com/denstiny/golang-language-server/biz/dal/cache/model"
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 func CreatePackage(pg model.Package) error {
 	db := DB.Table(model.PackageTableName)
@@ -16,6 +24,7 @@ type PackageFindParams struct {
 	Id          *int32
 	PackageName *string
 	Name        *string
+	Workspace   *string // 只返回该工作区和共享包(依赖、标准库)
 }
 
 func FindPackage(find PackageFindParams) ([]*model.Package, error) {
@@ -36,6 +45,9 @@ func FindPackage(find PackageFindParams) ([]*model.Package, error) {
 	if find.Name != nil {
 		db = db.Where("name=?", *find.Name)
 	}
+	if find.Workspace != nil {
+		db = db.Where("workspace=? or workspace=''", *find.Workspace)
+	}
 	var results []*model.Package
 	err = db.Find(&results).Error
 	if err != nil {
@@ -98,3 +110,176 @@ func FindPackageLibranyLikeName(name string) ([]*model.PackageLibrany, error) {
 	}
 	return results, nil
 }
+
+// MarkPackageIndexed 在包的所有索引写入完成后记录完成标记，和写入索引使用同一个事务
+func MarkPackageIndexed(tx *gorm.DB, packageId int64) error {
+	db := tx.Table(model.PackageTableName)
+	return db.Where("id=?", packageId).Update("index_done", true).Error
+}
+
+// IndexPackage 写入包和包中文件的全部索引以及依赖的 module，同名同版本同工作区的旧记录会被替换。
+// requires 为 go.mod 中 require 的 module 路径 -> 版本。
+// 包、索引、依赖和完成标记在同一个事务中提交，中途失败时不会留下不完整的包
+func IndexPackage(pkg *model.Package, files []*file.GoFile, requires map[string]string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := deletePackage(tx, pkg)
+		if err != nil {
+			return err
+		}
+		pkg.ID = 0
+		pkg.IndexDone = false
+		err = tx.Table(model.PackageTableName).Create(pkg).Error
+		if err != nil {
+			return err
+		}
+
+		var indexes []model.Index
+		for _, gf := range files {
+			indexes = append(indexes, FileIndexes(gf, pkg)...)
+		}
+		if len(indexes) > 0 {
+			err = tx.Table(model.IndexTableName).CreateInBatches(indexes, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		libs := packageLibranys(files, requires)
+		for i := range libs {
+			libs[i].ParentID = pkg.ID
+		}
+		if len(libs) > 0 {
+			err = tx.Table(model.PackageLibranyTablName).CreateInBatches(libs, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		err = MarkPackageIndexed(tx, pkg.ID)
+		if err != nil {
+			return err
+		}
+		pkg.IndexDone = true
+		return nil
+	})
+}
+
+// packageLibranys 包中的文件导入的包所属的依赖 module，不在 requires 中的导入(标准库、当前 module)不是依赖
+func packageLibranys(files []*file.GoFile, requires map[string]string) []model.PackageLibrany {
+	seen := make(map[string]bool)
+	var libs []model.PackageLibrany
+	for _, gf := range files {
+		if gf.File == nil {
+			continue
+		}
+		for _, spec := range gf.File.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			mod := requiredModule(path, requires)
+			if mod == "" || seen[mod] {
+				continue
+			}
+			seen[mod] = true
+			version := requires[mod]
+			libs = append(libs, model.PackageLibrany{PackageName: mod, Version: &version})
+		}
+	}
+	sort.Slice(libs, func(i, j int) bool {
+		return libs[i].PackageName < libs[j].PackageName
+	})
+	return libs
+}
+
+// requiredModule import 路径所属的 module，嵌套的 module 取最长的路径
+func requiredModule(path string, requires map[string]string) string {
+	var mod string
+	for m := range requires {
+		if (path == m || strings.HasPrefix(path, m+"/")) && len(m) > len(mod) {
+			mod = m
+		}
+	}
+	return mod
+}
+
+// FindPartialPackages 查找索引未完成的包
+func FindPartialPackages() ([]*model.Package, error) {
+	db := DB.Table(model.PackageTableName)
+	var results []*model.Package
+	err := db.Where("index_done=?", false).Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeletePartialPackages 删除没有完成标记的包和残留的索引，工作区中的包在打开工作区时会重新索引
+func DeletePartialPackages() error {
+	pkgs, err := FindPartialPackages()
+	if err != nil {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, pkg := range pkgs {
+			err := deletePackageRows(tx, pkg)
+			if err != nil {
+				return err
+			}
+			log.Info().Str("package", pkg.IndexName()).Msg("delete partial package")
+		}
+		return nil
+	})
+}
+
+// LookupPackageName 根据 import 路径查找包名，用于 file.GoFile.ResolveImportNames
+func LookupPackageName(path string) (string, bool) {
+	db := DB.Table(model.PackageTableName)
+	var pkg model.Package
+	err := db.Where("name=? and package_name<>''", path).Limit(1).Find(&pkg).Error
+	if err != nil || pkg.PackageName == "" {
+		return "", false
+	}
+	return pkg.PackageName, true
+}
+
+// ParseGoCode 解析 go 代码，没有别名的 import 使用索引中记录的真实包名。
+// 悬停、跳转和补全都通过这里解析，包名和路径最后一段不同时也能找到 import
+func ParseGoCode(filename string, code []byte) (*file.GoFile, error) {
+	gf, err := file.ParseGoCode(filename, code)
+	if err != nil {
+		return nil, err
+	}
+	gf.ResolveImportNames(LookupPackageName)
+	return gf, nil
+}
+
+// likePrefix 转义 like 的通配符，匹配以 prefix 开头的字符串，和 escape '\' 一起使用
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// SearchPackages 查找包名以 prefix 开头的包，不区分大小写，只返回该工作区和共享包(依赖、标准库)
+func SearchPackages(prefix string, workspace string) ([]*model.Package, error) {
+	db := DB.Table(model.PackageTableName)
+	db = db.Where(`package_name like ? escape '\'`, likePrefix(prefix))
+	db = db.Where("workspace=? or workspace=''", workspace)
+	var results []*model.Package
+	err := db.Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SearchPackagePaths 查找 import 路径以 prefix 开头的包，只返回该工作区和共享包(依赖、标准库)
+func SearchPackagePaths(prefix string, workspace string) ([]*model.Package, error) {
+	db := DB.Table(model.PackageTableName)
+	db = db.Where(`name like ? escape '\'`, likePrefix(prefix))
+	db = db.Where("workspace=? or workspace=''", workspace)
+	var results []*model.Package
+	err := db.Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
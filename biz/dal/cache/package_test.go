@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// useTestDB 使用 dbpath 的数据库代替全局的 DB，测试结束后恢复
+func useTestDB(t *testing.T, dbpath string) {
+	t.Helper()
+	db, err := openDB(dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = migrate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = old
+		closeDB(db)
+	})
+}
+
+func parseTestFile(t *testing.T, filename, code string) *file.GoFile {
+	t.Helper()
+	gf, err := file.ParseGoCode(filename, []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gf
+}
+
+func indexNames(t *testing.T, packageID int64) []string {
+	t.Helper()
+	id := int32(packageID)
+	indexes, err := FindIndex(IndexFindParams{PackageID: &id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, index := range indexes {
+		names = append(names, index.KeyWorld)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestIndexPackageSurvivesReopen(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "cache.db")
+	useTestDB(t, dbpath)
+
+	gf := parseTestFile(t, "/work/a/a.go", `package a
+
+var V int
+
+type T struct{ Name string }
+
+func F() {}
+`)
+	pkg := &model.Package{Name: "example.com/a", PackageName: "a"}
+	if err := IndexPackage(pkg, []*file.GoFile{gf}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !pkg.IndexDone {
+		t.Fatal("IndexPackage did not mark the package as indexed")
+	}
+
+	// 没有完成标记的包，重新打开时包和残留的索引都会被删除
+	partial := model.Package{Name: "example.com/b", PackageName: "b"}
+	if err := CreatePackage(partial); err != nil {
+		t.Fatal(err)
+	}
+	partials, err := FindPackage(PackageFindParams{Name: &partial.Name})
+	if err != nil || len(partials) != 1 {
+		t.Fatalf("FindPackage(%q) = %v, %v", partial.Name, partials, err)
+	}
+	if err := CreateIndex(model.Index{KeyWorld: "B", PackageID: int32(partials[0].ID)}); err != nil {
+		t.Fatal(err)
+	}
+
+	closeDB(DB)
+	useTestDB(t, dbpath)
+	if err := DeletePartialPackages(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"F", "Name", "T", "V"}
+	if got := indexNames(t, pkg.ID); !slices.Equal(got, want) {
+		t.Errorf("indexes after reopen = %v, want %v", got, want)
+	}
+	if got := indexNames(t, partials[0].ID); len(got) != 0 {
+		t.Errorf("partial package indexes after reopen = %v, want none", got)
+	}
+	if got, err := FindPackage(PackageFindParams{Name: &partial.Name}); err != nil || len(got) != 0 {
+		t.Errorf("partial package after reopen = %v, %v, want none", got, err)
+	}
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "cache.db"))
+
+	// 同一个仓库的两个 checkout，加上所有工作区共享的依赖包
+	packages := []struct {
+		pkg  model.Package
+		file string
+		code string
+	}{
+		{model.Package{Name: "example.com/m", PackageName: "m", Workspace: "/work/a"}, "/work/a/m.go", "package m\n\nfunc OnlyA() int { return 0 }\n"},
+		{model.Package{Name: "example.com/m", PackageName: "m", Workspace: "/work/b"}, "/work/b/m.go", "package m\n\nfunc OnlyB() string { return \"\" }\n"},
+		{model.Package{Name: "example.com/dep", PackageName: "dep"}, "/mod/example.com/dep@v1.0.0/dep.go", "package dep\n\nfunc Shared() {}\n"},
+	}
+	for _, p := range packages {
+		pkg := p.pkg
+		err := IndexPackage(&pkg, []*file.GoFile{parseTestFile(t, p.file, p.code)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		workspace string
+		want      []string
+	}{
+		{"/work/a", []string{"OnlyA", "Shared"}},
+		{"/work/b", []string{"OnlyB", "Shared"}},
+		{"/work/c", []string{"Shared"}},
+	}
+	for _, tt := range tests {
+		indexes, err := FindIndex(IndexFindParams{Workspace: &tt.workspace})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, index := range indexes {
+			names = append(names, index.KeyWorld)
+		}
+		sort.Strings(names)
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("FindIndex(workspace %s) = %v, want %v", tt.workspace, names, tt.want)
+		}
+
+		pkgs, err := FindPackage(PackageFindParams{Workspace: &tt.workspace})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pkg := range pkgs {
+			if pkg.Workspace != "" && pkg.Workspace != tt.workspace {
+				t.Errorf("FindPackage(workspace %s) returned package of workspace %s", tt.workspace, pkg.Workspace)
+			}
+		}
+	}
+
+	// 另一个工作区中的函数不能用来推断类型
+	if _, ok := (IndexResolver{Workspace: "/work/a"}).LookupFunc("m", "OnlyB"); ok {
+		t.Error("workspace /work/a resolved OnlyB from workspace /work/b")
+	}
+	if _, ok := (IndexResolver{Workspace: "/work/a"}).LookupFunc("m", "OnlyA"); !ok {
+		t.Error("workspace /work/a did not resolve its own OnlyA")
+	}
+}
+
+func TestParseGoCodeResolvesImportNames(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "cache.db"))
+	pkg := &model.Package{Name: "example.com/api/v1client", PackageName: "client"}
+	if err := IndexPackage(pkg, []*file.GoFile{parseTestFile(t, "/mod/api/v1client/client.go", "package client\n")}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	gf, err := ParseGoCode("/work/main.go", []byte("package main\n\nimport (\n\t\"example.com/api/v1client\"\n\tl \"example.com/api/v1client\"\n)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"client", "l"} {
+		if spec, ok := gf.ImportByName(name); !ok || spec.Path != "example.com/api/v1client" {
+			t.Errorf("ImportByName(%q) = %+v, %v", name, spec, ok)
+		}
+	}
+	if _, ok := gf.ImportByName("v1client"); ok {
+		t.Errorf("assumed import name v1client should be replaced by client")
+	}
+}
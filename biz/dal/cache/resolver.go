@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"strings"
+)
+
+// IndexResolver 通过索引解析其他包中的类型，实现 file.TypeResolver
+type IndexResolver struct {
+	Workspace string            // 只查询该工作区和共享包的索引
+	Build     *file.BuildConfig // 不为空时忽略构建约束不成立的文件中的符号
+	File      *file.GoFile      // 当前文件，用于把类型中的包名转换为 import 路径，不同路径的同名包不会混在一起
+}
+
+// filter 按构建约束过滤查询结果
+func (r IndexResolver) filter(indexes []*model.Index) []*model.Index {
+	if r.Build == nil {
+		return indexes
+	}
+	return FilterBuild(indexes, *r.Build)
+}
+
+// packagePath 把包名转换为 import 路径，优先使用当前文件的 import，
+// 当前文件没有导入时(比如其他包中的类型嵌入的类型)按包名在工作区和共享包中查找
+func (r IndexResolver) packagePath(pkg string) string {
+	if r.File != nil {
+		if spec, ok := r.File.ImportByName(pkg); ok {
+			return spec.Path
+		}
+		for _, spec := range r.File.Imports {
+			if !spec.IsBlank() && !spec.IsDot() && spec.PackageName() == pkg {
+				return spec.Path
+			}
+		}
+	}
+	var paths []string
+	err := DB.Table(model.PackageTableName).
+		Where("workspace = ? or workspace = ''", r.Workspace).
+		Where("package_name = ?", pkg).
+		Limit(1).Pluck("name", &paths).Error
+	if err != nil || len(paths) == 0 {
+		return pkg
+	}
+	return paths[0]
+}
+
+func (r IndexResolver) LookupType(pkg, name string) (*file.TypeSpec, []file.FuncSpec, bool) {
+	path := r.packagePath(pkg)
+	var types []*model.Index
+	err := DB.Table(model.IndexTableName).
+		Where("workspace = ? or workspace = ''", r.Workspace).
+		Where("package_id in (?)", packageIDs(path)).
+		Where("key_world = ? and type = ?", name, model.IndexTypeType).
+		Find(&types).Error
+	types = r.filter(types)
+	if err != nil || len(types) == 0 {
+		return nil, nil, false
+	}
+
+	var members []*model.Index
+	err = DB.Table(model.IndexTableName).
+		Where("workspace = ? or workspace = ''", r.Workspace).
+		Where("package_id in (?) and comparable = ?", packageIDs(path), name).
+		Where("type in ?", []int32{model.IndexTypeField, model.IndexTypeEmbed}).
+		Find(&members).Error
+	if err != nil {
+		return nil, nil, false
+	}
+	recvMethods, err := FindMethods(path, name, r.Workspace)
+	if err != nil {
+		return nil, nil, false
+	}
+	members = append(members, recvMethods...)
+
+	t := &file.TypeSpec{Name: name}
+	// Extra 是 TypeSpec.Decl()，泛型类型没有保存类型参数，不还原底层类型
+	if typ, ok := strings.CutPrefix(types[0].Extra, name+" "); ok {
+		t.Type = typ
+	}
+	var methods []file.FuncSpec
+	for _, m := range r.filter(members) {
+		switch m.Type {
+		case model.IndexTypeField:
+			fieldName := m.KeyWorld
+			tags := FieldTags(m)
+			t.Fields = append(t.Fields, file.TypeInfoSpec{Name: &fieldName, Type: m.Extra, Tag: file.FormatTag(tags), Tags: tags})
+		case model.IndexTypeMethod:
+			typ, pointer, ok := ParseMethodExtra(m.Extra)
+			if !ok {
+				// 接口的方法没有接收者
+				t.Methods = append(t.Methods, file.FuncSpec{Name: m.KeyWorld, Type: typ})
+				continue
+			}
+			methods = append(methods, file.FuncSpec{Name: m.KeyWorld, Type: typ, Recv: &file.RecvSpec{Type: name, Pointer: pointer}})
+		case model.IndexTypeEmbed:
+			t.Embeds = append(t.Embeds, parseEmbedIndex(m))
+		}
+	}
+	return t, methods, true
+}
+
+// parseEmbedIndex 从索引还原嵌入类型，比如 *ast.File
+func parseEmbedIndex(m *model.Index) file.EmbedSpec {
+	embed := file.EmbedSpec{Type: m.Extra, Name: m.KeyWorld}
+	typ := m.Extra
+	if strings.HasPrefix(typ, "*") {
+		embed.Pointer = true
+		typ = typ[1:]
+	}
+	if pkg, _, ok := strings.Cut(typ, "."); ok {
+		embed.Package = pkg
+	}
+	return embed
+}
+
+// LookupFunc 实现 file.FuncResolver，返回值从索引中保存的函数签名解析
+func (r IndexResolver) LookupFunc(pkg, name string) (*file.FuncSpec, bool) {
+	var indexes []*model.Index
+	err := DB.Table(model.IndexTableName).
+		Where("workspace = ? or workspace = ''", r.Workspace).
+		Where("package_id in (?)", packageIDs(r.packagePath(pkg))).
+		Where("key_world = ? and type = ?", name, model.IndexTypeFunc).
+		Find(&indexes).Error
+	indexes = r.filter(indexes)
+	if err != nil || len(indexes) == 0 {
+		return nil, false
+	}
+	fn, err := file.ParseFuncSignature(name, indexes[0].Extra)
+	if err != nil {
+		return nil, false
+	}
+	return fn, true
+}
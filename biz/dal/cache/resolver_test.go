@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIndexResolverLookupType(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "cache.db"))
+
+	// 两个包名都是 config 的包
+	packages := []struct {
+		pkg  model.Package
+		file string
+		code string
+	}{
+		{model.Package{Name: "example.com/a/config", PackageName: "config"}, "/mod/a/config/config.go", `package config
+
+type Config struct{ A int }
+
+func (c *Config) Load() error { return nil }
+
+func (c Config) Name() string { return "" }
+`},
+		{model.Package{Name: "example.com/b/config", PackageName: "config"}, "/mod/b/config/config.go", `package config
+
+type Config struct{ B string }
+`},
+	}
+	for _, p := range packages {
+		pkg := p.pkg
+		err := IndexPackage(&pkg, []*file.GoFile{parseTestFile(t, p.file, p.code)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		importPath string
+		fields     []string
+		methods    map[string]bool // 方法名 -> 是否为指针接收者
+	}{
+		{"example.com/a/config", []string{"A"}, map[string]bool{"Load": true, "Name": false}},
+		{"example.com/b/config", []string{"B"}, map[string]bool{}},
+	}
+	for _, tt := range tests {
+		gf := parseTestFile(t, "/work/main.go", "package main\n\nimport \""+tt.importPath+"\"\n\nvar c config.Config\n")
+		typ, methods, ok := IndexResolver{File: gf}.LookupType("config", "Config")
+		if !ok {
+			t.Errorf("%s: LookupType(config.Config) not found", tt.importPath)
+			continue
+		}
+		var fields []string
+		for _, field := range typ.Fields {
+			fields = append(fields, *field.Name)
+		}
+		if len(fields) != len(tt.fields) || fields[0] != tt.fields[0] {
+			t.Errorf("%s: fields = %v, want %v", tt.importPath, fields, tt.fields)
+		}
+		if len(methods) != len(tt.methods) {
+			t.Errorf("%s: methods = %v, want %v", tt.importPath, methods, tt.methods)
+		}
+		for _, method := range methods {
+			pointer, ok := tt.methods[method.Name]
+			if !ok || method.Recv == nil || method.Recv.Pointer != pointer {
+				t.Errorf("%s: method %s recv = %+v, want pointer %v", tt.importPath, method.Name, method.Recv, pointer)
+			}
+		}
+	}
+}
+
+func TestMethodExtra(t *testing.T) {
+	tests := []struct {
+		fn      file.FuncSpec
+		extra   string
+		recv    bool
+		pointer bool
+	}{
+		{file.FuncSpec{Type: "func() error", Recv: &file.RecvSpec{Type: "T", Pointer: true}}, "(*T) func() error", true, true},
+		{file.FuncSpec{Type: "func() string", Recv: &file.RecvSpec{Type: "T"}}, "(T) func() string", true, false},
+		{file.FuncSpec{Type: "func(p []byte) (int, error)"}, "func(p []byte) (int, error)", false, false},
+	}
+	for _, tt := range tests {
+		extra := MethodExtra(tt.fn)
+		if extra != tt.extra {
+			t.Errorf("MethodExtra(%+v) = %q, want %q", tt.fn, extra, tt.extra)
+		}
+		typ, pointer, recv := ParseMethodExtra(extra)
+		if typ != tt.fn.Type || pointer != tt.pointer || recv != tt.recv {
+			t.Errorf("ParseMethodExtra(%q) = %q, %v, %v", extra, typ, pointer, recv)
+		}
+	}
+}
+
+func TestIndexResolverFieldTags(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "cache.db"))
+
+	pkg := model.Package{Name: "example.com/model", PackageName: "model"}
+	gf := parseTestFile(t, "/mod/model/model.go", "package model\n\n"+
+		"type User struct {\n"+
+		"	ID   int    `json:\"id\" gorm:\"primary_key\"`\n"+
+		"	Name string\n"+
+		"}\n")
+	if err := IndexPackage(&pkg, []*file.GoFile{gf}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, _, ok := IndexResolver{}.LookupType("model", "User")
+	if !ok {
+		t.Fatal("LookupType(model.User) not found")
+	}
+	tags := make(map[string]string)
+	for _, field := range typ.Fields {
+		tags[*field.Name] = field.Tag
+		if *field.Name == "ID" {
+			if v, ok := file.LookupTag(field.Tags, "gorm"); !ok || v != "primary_key" {
+				t.Errorf("ID gorm tag = %q, %v", v, ok)
+			}
+		}
+	}
+	if tags["ID"] != `json:"id" gorm:"primary_key"` || tags["Name"] != "" {
+		t.Errorf("indexed field tags = %q", tags)
+	}
+
+	// 标签按键值对保存，Extra 只有字段类型
+	var id model.Index
+	if err := DB.Table(model.IndexTableName).Where("key_world = ? and package_id = ?", "ID", pkg.ID).First(&id).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Tag{{Key: "json", Value: "id"}, {Key: "gorm", Value: "primary_key"}}
+	if id.Extra != "int" || !slices.Equal(id.Tags, want) {
+		t.Errorf("ID index extra = %q, tags = %v, want int, %v", id.Extra, id.Tags, want)
+	}
+}
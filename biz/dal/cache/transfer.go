@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"gorm.io/gorm"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ExportVersion 导出文件的格式版本，格式不兼容时递增
+const ExportVersion = 1
+
+// 导出文件中的路径(包括工作区)都以下面的前缀开头，导入时替换成本机的目录
+const (
+	ModCacheRoot  = "$GOMODCACHE"
+	WorkspaceRoot = "$WORKSPACE"
+)
+
+// ExportFile 是 index export 写出的内容，序列化为 gzip 压缩的 json
+type ExportFile struct {
+	Version  int                     `json:"version"`
+	Packages []*model.Package        `json:"packages"`
+	Indexes  []*model.Index          `json:"indexes"`
+	Libranys []*model.PackageLibrany `json:"libranys"`
+}
+
+// TransferRoots 本机的 module 缓存目录和工作区目录
+type TransferRoots struct {
+	ModCache  string
+	Workspace string
+}
+
+// ExportIndex 导出 modules 中的包(包括子包)、索引以及依赖关系，modules 为空时导出全部
+func ExportIndex(w io.Writer, modules []string, roots TransferRoots) error {
+	var out = ExportFile{Version: ExportVersion}
+
+	db := DB.Table(model.PackageTableName).Where("index_done=?", true)
+	if len(modules) > 0 {
+		// module 中的子包的 import 路径以 module 路径加 / 开头
+		match := DB.Where(`name = ? or name like ? escape '\'`, modules[0], likePrefix(modules[0]+"/"))
+		for _, module := range modules[1:] {
+			match = match.Or(`name = ? or name like ? escape '\'`, module, likePrefix(module+"/"))
+		}
+		db = db.Where(match)
+	}
+	err := db.Find(&out.Packages).Error
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(out.Packages))
+	for _, pkg := range out.Packages {
+		ids = append(ids, pkg.ID)
+	}
+	if len(ids) > 0 {
+		err = DB.Table(model.IndexTableName).Where("package_id in ?", ids).Find(&out.Indexes).Error
+		if err != nil {
+			return err
+		}
+		err = DB.Table(model.PackageLibranyTablName).Where("parent_id in ?", ids).Find(&out.Libranys).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, pkg := range out.Packages {
+		pkg.Workspace = relativePath(pkg.Workspace, roots)
+	}
+	for _, index := range out.Indexes {
+		index.FilePath = relativePath(index.FilePath, roots)
+		index.Workspace = relativePath(index.Workspace, roots)
+	}
+
+	zw := gzip.NewWriter(w)
+	err = json.NewEncoder(zw).Encode(&out)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportIndex 导入 ExportIndex 导出的内容，已存在的同名同版本包会被覆盖
+func ImportIndex(r io.Reader, roots TransferRoots) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	var in ExportFile
+	err = json.NewDecoder(zr).Decode(&in)
+	if err != nil {
+		return err
+	}
+	if in.Version != ExportVersion {
+		return fmt.Errorf("import index: unsupported version %d, want %d", in.Version, ExportVersion)
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// 旧 id -> 新 id
+		ids := make(map[int64]int64, len(in.Packages))
+		for _, pkg := range in.Packages {
+			pkg.Workspace = absolutePath(pkg.Workspace, roots)
+			err := deletePackage(tx, pkg)
+			if err != nil {
+				return err
+			}
+
+			oldID := pkg.ID
+			pkg.ID = 0
+			err = tx.Table(model.PackageTableName).Create(pkg).Error
+			if err != nil {
+				return err
+			}
+			ids[oldID] = pkg.ID
+		}
+
+		for _, index := range in.Indexes {
+			id, ok := ids[int64(index.PackageID)]
+			if !ok {
+				return fmt.Errorf("import index: index %s refers to unknown package %d", index.KeyWorld, index.PackageID)
+			}
+			index.ID = 0
+			index.PackageID = int32(id)
+			index.FilePath = absolutePath(index.FilePath, roots)
+			index.Workspace = absolutePath(index.Workspace, roots)
+		}
+		if len(in.Indexes) > 0 {
+			err := tx.Table(model.IndexTableName).CreateInBatches(in.Indexes, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, lib := range in.Libranys {
+			id, ok := ids[lib.ParentID]
+			if !ok {
+				return fmt.Errorf("import index: dependency %s refers to unknown package %d", lib.PackageName, lib.ParentID)
+			}
+			lib.ID = 0
+			lib.ParentID = id
+		}
+		if len(in.Libranys) > 0 {
+			err := tx.Table(model.PackageLibranyTablName).CreateInBatches(in.Libranys, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func deletePackage(tx *gorm.DB, pkg *model.Package) error {
+	var olds []*model.Package
+	err := tx.Table(model.PackageTableName).
+		Where("name=? and version=? and workspace=?", pkg.Name, pkg.Version, pkg.Workspace).
+		Find(&olds).Error
+	if err != nil {
+		return err
+	}
+	for _, old := range olds {
+		err = deletePackageRows(tx, old)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletePackageRows 删除包和包的索引、依赖记录
+func deletePackageRows(tx *gorm.DB, pkg *model.Package) error {
+	err := tx.Table(model.IndexTableName).Where("package_id = ?", pkg.ID).Delete(&model.Index{}).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Table(model.PackageLibranyTablName).Where("parent_id = ?", pkg.ID).Delete(&model.PackageLibrany{}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Table(model.PackageTableName).Delete(pkg).Error
+}
+
+func relativePath(p string, roots TransferRoots) string {
+	if p == "" {
+		return p
+	}
+	for _, root := range [][2]string{{roots.ModCache, ModCacheRoot}, {roots.Workspace, WorkspaceRoot}} {
+		if root[0] == "" {
+			continue
+		}
+		if p == root[0] {
+			return root[1]
+		}
+		rel, err := filepath.Rel(root[0], p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		return root[1] + "/" + filepath.ToSlash(rel)
+	}
+	return p
+}
+
+func absolutePath(p string, roots TransferRoots) string {
+	switch p {
+	case ModCacheRoot:
+		return roots.ModCache
+	case WorkspaceRoot:
+		return roots.Workspace
+	}
+	if rel, ok := strings.CutPrefix(p, ModCacheRoot+"/"); ok {
+		return filepath.Join(roots.ModCache, filepath.FromSlash(rel))
+	}
+	if rel, ok := strings.CutPrefix(p, WorkspaceRoot+"/"); ok {
+		return filepath.Join(roots.Workspace, filepath.FromSlash(rel))
+	}
+	return p
+}
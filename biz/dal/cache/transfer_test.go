@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestExportImportIndex(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "export.db"))
+	packages := []struct {
+		pkg  model.Package
+		file string
+		code string
+	}{
+		{model.Package{Name: "example.com/m", PackageName: "m", Workspace: "/work/m"}, "/work/m/m.go", "package m\n\nimport (\n\t\"fmt\"\n\t\"github.com/x/dep/sub\"\n)\n\nfunc M() {}\n"},
+		{model.Package{Name: "example.com/m/sub", PackageName: "sub", Workspace: "/work/m"}, "/work/m/sub/sub.go", "package sub\n\nfunc Sub() {}\n"},
+		{model.Package{Name: "example.com/mother", PackageName: "mother", Workspace: "/work/m"}, "/work/m/mother.go", "package mother\n\nfunc Mother() {}\n"},
+		{model.Package{Name: "example.com/other", PackageName: "other"}, "/mod/example.com/other@v1.0.0/other.go", "package other\n\nfunc Other() {}\n"},
+	}
+	requires := map[string]string{"github.com/x/dep": "v1.2.0", "github.com/x/unused": "v0.1.0"}
+	for _, p := range packages {
+		pkg := p.pkg
+		err := IndexPackage(&pkg, []*file.GoFile{parseTestFile(t, p.file, p.code)}, requires)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := ExportIndex(&buf, []string{"example.com/m"}, TransferRoots{ModCache: "/mod", Workspace: "/work/m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useTestDB(t, filepath.Join(t.TempDir(), "import.db"))
+	err = ImportIndex(&buf, TransferRoots{ModCache: "/home/ci/mod", Workspace: "/home/ci/m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := FindPackage(PackageFindParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	ids := make(map[string]int64)
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+		ids[pkg.Name] = pkg.ID
+		if !pkg.IndexDone || pkg.Workspace != "/home/ci/m" {
+			t.Errorf("imported package %s: index_done = %v, workspace = %q", pkg.Name, pkg.IndexDone, pkg.Workspace)
+		}
+	}
+	sort.Strings(names)
+	if want := []string{"example.com/m", "example.com/m/sub"}; !slices.Equal(names, want) {
+		t.Errorf("imported packages = %v, want %v", names, want)
+	}
+
+	var indexes []*model.Index
+	err = DB.Table(model.IndexTableName).Find(&indexes).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, index := range indexes {
+		files[index.KeyWorld] = index.FilePath
+	}
+	want := map[string]string{"M": "/home/ci/m/m.go", "Sub": "/home/ci/m/sub/sub.go"}
+	if len(files) != len(want) || files["M"] != want["M"] || files["Sub"] != want["Sub"] {
+		t.Errorf("imported indexes = %v, want %v", files, want)
+	}
+
+	var libs []*model.PackageLibrany
+	err = DB.Table(model.PackageLibranyTablName).Find(&libs).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(libs) != 1 || libs[0].PackageName != "github.com/x/dep" || libs[0].Version == nil || *libs[0].Version != "v1.2.0" ||
+		libs[0].ParentID != ids["example.com/m"] {
+		t.Errorf("imported dependencies = %+v, want github.com/x/dep@v1.2.0 of example.com/m", libs)
+	}
+}
+
+func TestImportIndexUnknownPackage(t *testing.T) {
+	useTestDB(t, filepath.Join(t.TempDir(), "import.db"))
+	version := "v1.0.0"
+	tests := []struct {
+		name string
+		in   ExportFile
+	}{
+		{"index", ExportFile{
+			Version:  ExportVersion,
+			Packages: []*model.Package{{ID: 1, Name: "example.com/m", IndexDone: true}},
+			Indexes:  []*model.Index{{KeyWorld: "M", PackageID: 2}},
+		}},
+		{"dependency", ExportFile{
+			Version:  ExportVersion,
+			Packages: []*model.Package{{ID: 1, Name: "example.com/m", IndexDone: true}},
+			Libranys: []*model.PackageLibrany{{ParentID: 2, PackageName: "github.com/x/dep", Version: &version}},
+		}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if err := json.NewEncoder(zw).Encode(&tt.in); err != nil {
+			t.Fatal(err)
+		}
+		zw.Close()
+		if err := ImportIndex(&buf, TransferRoots{}); err == nil {
+			t.Errorf("%s: ImportIndex with unknown package id succeeded", tt.name)
+		}
+		// 出错时整个导入回滚
+		if pkgs, err := FindPackage(PackageFindParams{}); err != nil || len(pkgs) != 0 {
+			t.Errorf("%s: packages after failed import = %v, %v", tt.name, pkgs, err)
+		}
+	}
+}
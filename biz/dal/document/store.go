@@ -0,0 +1,37 @@
+package document
+
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"os"
+	"sync"
+)
+
+// 编辑器中打开的文件内容，包括还没有保存的修改
+var (
+	mu        sync.RWMutex
+	documents = make(map[string][]byte) // 统一写法后的 uri -> 内容
+)
+
+// Set 保存打开的文件内容。客户端发来的 uri 和服务端用文件路径拼出的 uri 写法可能不同，统一后再作为 key
+func Set(uri string, text []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	documents[file.NormalizeURI(uri)] = text
+}
+
+func Delete(uri string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(documents, file.NormalizeURI(uri))
+}
+
+// Read 返回打开的文件内容，没有打开时从磁盘读取
+func Read(uri string) ([]byte, error) {
+	mu.RLock()
+	text, ok := documents[file.NormalizeURI(uri)]
+	mu.RUnlock()
+	if ok {
+		return text, nil
+	}
+	return os.ReadFile(file.URIToPath(uri))
+}
@@ -0,0 +1,28 @@
+package document
+
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"testing"
+)
+
+func TestReadNormalizedURI(t *testing.T) {
+	tests := []struct {
+		client string // 客户端发来的 uri
+		path   string // 服务端读取时使用的文件路径
+	}{
+		{"file:///work/a.go", "/work/a.go"},
+		{"file:///work/my%20dir/a.go", "/work/my dir/a.go"},
+		{"file:///C%3A/work/a.go", "c:/work/a.go"},
+	}
+	for _, tt := range tests {
+		Set(tt.client, []byte("package a\n"))
+		text, err := Read(file.PathToURI(tt.path))
+		if err != nil || string(text) != "package a\n" {
+			t.Errorf("Read(%q) after Set(%q) = %q, %v", file.PathToURI(tt.path), tt.client, text, err)
+		}
+		Delete(file.PathToURI(tt.path))
+		if _, err := Read(tt.client); err == nil {
+			t.Errorf("Read(%q) after Delete should fall back to the missing file", tt.client)
+		}
+	}
+}
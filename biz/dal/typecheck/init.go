@@ -0,0 +1,60 @@
+package typecheck
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/biz/flags"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"github.com/denstiny/golang-language-server/pkg/semantic"
+	"github.com/rs/zerolog/log"
+	"path/filepath"
+)
+
+// Checker 类型检查模式未开启时为 nil
+var (
+	Checker *semantic.Checker
+)
+
+// Init 按照命令行参数开启类型检查模式，需要在 flags.Parse 之后调用
+func Init() {
+	if !flags.SERVICE_SEMANTIC {
+		return
+	}
+	Checker = semantic.NewChecker(int64(flags.SERVICE_SEMANTIC_MEMORY) << 20)
+	// 打开的文件使用编辑器中还没有保存的内容
+	Checker.ReadFile = func(filename string) ([]byte, error) {
+		return document.Read(file.PathToURI(filename))
+	}
+}
+
+// Load 返回文件所在包的类型检查结果，未开启类型检查模式或检查失败时返回 false，调用方应回退到语法分析
+func Load(filename string) (*semantic.Package, bool) {
+	if Checker == nil {
+		return nil, false
+	}
+	pkg, err := Checker.Load(filepath.Dir(filename))
+	if err != nil {
+		log.Debug().Str("file", filename).Msg("type check failed: " + err.Error())
+		return nil, false
+	}
+	if _, ok := pkg.Files[filename]; !ok {
+		// 测试文件等不在检查范围内的文件
+		return nil, false
+	}
+	return pkg, true
+}
+
+// SetBuild 设置类型检查使用的目标平台和构建标签
+func SetBuild(build file.BuildConfig) {
+	if Checker == nil {
+		return
+	}
+	Checker.SetBuild(build)
+}
+
+// Invalidate 文件在编辑器中打开、修改、保存或者关闭后丢弃所在包的类型检查结果
+func Invalidate(filename string) {
+	if Checker == nil {
+		return
+	}
+	Checker.Invalidate(filepath.Dir(filename))
+}
@@ -14,6 +14,9 @@ var (
 	SERVICE_STDIO      bool
 	SERVICE_TCP        bool
 	SERVICE_PROT       int
+
+	SERVICE_SEMANTIC        bool // 使用 go/types 对打开的包做完整类型检查
+	SERVICE_SEMANTIC_MEMORY int  // 类型检查结果缓存的内存上限，单位 MB
 )
 
 func init() {
@@ -22,7 +25,13 @@ func init() {
 	flag.BoolVar(&SERVICE_STDIO, "stdio", false, "标准输出")
 	flag.BoolVar(&SERVICE_TCP, "tcp", false, "rpc连接方式")
 	flag.IntVar(&SERVICE_PROT, "port", 9999, "端口")
+	flag.BoolVar(&SERVICE_SEMANTIC, "semantic", false, "开启类型检查模式，提供更精确的悬停、跳转和补全")
+	flag.IntVar(&SERVICE_SEMANTIC_MEMORY, "semantic_memory", 256, "类型检查缓存的内存上限(MB)")
 	flag.Usage = Help
+}
+
+// Parse 解析命令行参数并创建配置目录，由 main 在使用任何配置之前调用
+func Parse() {
 	flag.Parse()
 
 	if _, err := os.Stat(SERVICE_CONFIG_DIR); os.IsNotExist(err) {
@@ -40,4 +49,7 @@ func Help() {
 	flag.PrintDefaults()
 	fmt.Fprintln(os.Stderr, "Examples:")
 	fmt.Fprintf(os.Stderr, "  %s -port 8080 -config_dir /path/to/config -debug\n", conts.SERVICE_NAME)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintf(os.Stderr, "  %s index export -workspace <dir> <file> [module...]  导出索引\n", conts.SERVICE_NAME)
+	fmt.Fprintf(os.Stderr, "  %s index import -workspace <dir> <file>              导入索引\n", conts.SERVICE_NAME)
 }
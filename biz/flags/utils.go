@@ -3,6 +3,7 @@ package flags
 import (
 	"os"
 	"os/user"
+	"path/filepath"
 	"runtime"
 )
 
@@ -22,3 +23,14 @@ func GetHome() string {
 	}
 	return u.HomeDir
 }
+
+// GetGoModCache 返回本机的 go module 缓存目录
+func GetGoModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	return filepath.Join(GetHome(), "go", "pkg", "mod")
+}
@@ -0,0 +1,66 @@
+package completion
+
+import (
+	"pkg.nimblebun.works/go-lsp"
+)
+
+// 预声明的标识符
+var (
+	builtinTypes = []string{
+		"any", "bool", "byte", "comparable", "complex64", "complex128", "error", "float32", "float64",
+		"int", "int8", "int16", "int32", "int64", "rune", "string",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+	}
+	builtinFuncs = map[string]string{
+		"append":  "func append(slice []Type, elems ...Type) []Type",
+		"cap":     "func cap(v Type) int",
+		"clear":   "func clear[T ~[]Type | ~map[Type]Type1](t T)",
+		"close":   "func close(c chan<- Type)",
+		"complex": "func complex(r, i FloatType) ComplexType",
+		"copy":    "func copy(dst, src []Type) int",
+		"delete":  "func delete(m map[Type]Type1, key Type)",
+		"imag":    "func imag(c ComplexType) FloatType",
+		"len":     "func len(v Type) int",
+		"make":    "func make(t Type, size ...IntegerType) Type",
+		"max":     "func max[T cmp.Ordered](x T, y ...T) T",
+		"min":     "func min[T cmp.Ordered](x T, y ...T) T",
+		"new":     "func new(Type) *Type",
+		"panic":   "func panic(v any)",
+		"print":   "func print(args ...Type)",
+		"println": "func println(args ...Type)",
+		"real":    "func real(c ComplexType) FloatType",
+		"recover": "func recover() any",
+	}
+	builtinConsts = []string{"true", "false", "iota"}
+)
+
+func builtinCandidates() []candidate {
+	var candidates []candidate
+	for _, name := range builtinTypes {
+		kind := lsp.CIKClass
+		if name == "error" || name == "any" || name == "comparable" {
+			kind = lsp.CIKInterface
+		}
+		candidates = append(candidates, candidate{label: name, kind: kind, detail: "type " + name})
+	}
+	for name, detail := range builtinFuncs {
+		candidates = append(candidates, candidate{label: name, kind: lsp.CIKFunction, detail: detail})
+	}
+	for _, name := range builtinConsts {
+		typ := "bool"
+		if name == "iota" {
+			typ = "int"
+		}
+		candidates = append(candidates, candidate{label: name, kind: lsp.CIKConstant, detail: "const " + name, typ: func() string { return typ }})
+	}
+	candidates = append(candidates, candidate{label: "nil", kind: lsp.CIKValue, detail: "var nil Type"})
+	return candidates
+}
+
+func keywordCandidates(keywords []string) []candidate {
+	candidates := make([]candidate, 0, len(keywords))
+	for _, keyword := range keywords {
+		candidates = append(candidates, candidate{label: keyword, kind: lsp.CIKKeyword, detail: keyword})
+	}
+	return candidates
+}
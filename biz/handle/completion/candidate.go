@@ -0,0 +1,365 @@
+package completion
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/biz/dal/typecheck"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"strings"
+)
+
+// identCandidates 不在选择器后面时的候选项: 局部变量和参数、包级别的符号、内置符号和关键字。
+// 包级别声明的开头和 switch 的大括号中只能出现关键字
+func (r *request) identCandidates() []candidate {
+	// snippet 在前面，和关键字同名时代替关键字
+	keywords := append(r.snippetCandidates(), keywordCandidates(contextKeywords(r.context))...)
+	switch r.context.Kind {
+	case file.ContextFileStart, file.ContextTopLevel, file.ContextSwitchBody:
+		return keywords
+	}
+	var candidates []candidate
+	for _, decl := range r.gf.VisibleDecls(r.pos) {
+		cand := r.declCandidate(r.gf, decl)
+		switch {
+		case decl.Type == file.DeclSpecTypeImport:
+			cand.scope = stdScore
+		case decl.Visible == token.NoPos:
+			cand.scope = packageScore
+		default:
+			cand.scope = highScore
+		}
+		candidates = append(candidates, cand)
+	}
+	candidates = append(candidates, r.packageCandidates()...)
+	candidates = append(candidates, builtinCandidates()...)
+	candidates = append(candidates, r.unimportedCandidates()...)
+	candidates = append(candidates, keywords...)
+	return candidates
+}
+
+// selectorCandidates x. 后面的候选项，x 是导入的包时返回包的导出成员，
+// x 是值时推断它的类型，返回字段、方法以及嵌入类型提升的成员，最后是类型适用的后缀补全
+func (r *request) selectorCandidates() []candidate {
+	if r.selector != "" && !strings.Contains(r.selector, ".") {
+		// 局部变量可以遮蔽包名
+		decl, _ := r.gf.LookupVisible(r.selector, r.pos)
+		if decl == nil || decl.Type == file.DeclSpecTypeImport {
+			if spec, ok := r.gf.ImportByName(r.selector); ok {
+				return r.importCandidates(spec)
+			}
+		}
+		// 还没有导入的包
+		if decl == nil && !r.declared(r.selector) {
+			return r.unimportedMemberCandidates(r.selector)
+		}
+	}
+
+	dot := r.pos - token.Pos(len(r.prefix)) - 1
+	base := r.gf.SelectorBase(dot)
+	if base == nil {
+		return nil
+	}
+	value := first(r.gf.InferType(base, r.resolver))
+	postfix := r.postfixCandidates(base, value)
+	typ := strings.TrimPrefix(value, "*")
+	// 泛型类型的实例 List[int]，成员属于 List
+	if i := strings.Index(typ, "["); i > 0 {
+		typ = typ[:i]
+	}
+	if typ == "" || !isTypeName(typ) {
+		return postfix
+	}
+
+	pkg, name, ok := strings.Cut(typ, ".")
+	// 文件中引用其他包使用的名称，成员的类型需要加上它
+	qualifier := pkg
+	if ok {
+		// 类型中的包名是文件中引用包的名称，可能是别名
+		if spec, ok := r.gf.ImportByName(pkg); ok {
+			pkg = spec.PackageName()
+		}
+	} else {
+		pkg, name = "", typ
+	}
+
+	gopkg := r.goPackage()
+	var candidates []candidate
+	// 变量是可寻址的，值类型的变量也可以调用指针接收者的方法
+	for _, member := range gopkg.MembersOf(pkg, name, true, r.resolver) {
+		// 其他包的类型只能访问导出的成员
+		if pkg != "" && pkg != gopkg.Name && !token.IsExported(member.Name()) {
+			continue
+		}
+		cand := memberCandidate(member)
+		if pkg != "" && pkg != gopkg.Name {
+			cand.typ = qualifiedType(cand.typ, qualifier)
+		}
+		candidates = append(candidates, cand)
+	}
+	return append(candidates, postfix...)
+}
+
+func memberCandidate(member file.MemberSpec) candidate {
+	if member.Field != nil {
+		field := member.Field
+		return candidate{
+			label: *field.Name,
+			kind:  lsp.CIKField,
+			typ:   func() string { return field.Type },
+			resolve: func() (string, string) {
+				detail := "field " + *field.Name + " " + field.Type
+				if field.Tag != "" {
+					detail += " `" + field.Tag + "`"
+				}
+				return detail, field.Comment
+			},
+		}
+	}
+	method := member.Method
+	return candidate{
+		label: method.Name,
+		kind:  lsp.CIKMethod,
+		typ:   func() string { return method.Type },
+		resolve: func() (string, string) {
+			detail := "func " + method.Name + strings.TrimPrefix(method.Type, "func")
+			if method.Recv != nil {
+				recv := method.Recv.Type
+				if method.Recv.Pointer {
+					recv = "*" + recv
+				}
+				detail = "func (" + recv + ") " + method.Name + strings.TrimPrefix(method.Type, "func")
+			}
+			return detail, method.Comment
+		},
+	}
+}
+
+// isTypeName 类型是 T 或者 pkg.T 形式的命名类型，切片、map 等类型没有成员
+func isTypeName(typ string) bool {
+	for _, part := range strings.Split(typ, ".") {
+		if !token.IsIdentifier(part) {
+			return false
+		}
+	}
+	return strings.Count(typ, ".") <= 1
+}
+
+func first(types []string) string {
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+func (r *request) declCandidate(gf *file.GoFile, decl *file.DeclSpec) candidate {
+	cand := candidate{label: decl.Name}
+	// 推断类型可能需要查询索引，放到 resolve 时计算
+	cand.resolve = func() (string, string) {
+		return gf.DeclString(decl, r.resolver), gf.DocAt(decl.Pos)
+	}
+	switch decl.Type {
+	case file.DeclSpecTypeVar, file.DeclSpecTypeParam, file.DeclSpecTypeConst, file.DeclSpecTypeFunc:
+		cand.typ = func() string { return gf.DeclType(decl, r.resolver) }
+	}
+	switch decl.Type {
+	case file.DeclSpecTypeVar, file.DeclSpecTypeParam:
+		cand.kind = lsp.CIKVariable
+	case file.DeclSpecTypeConst:
+		cand.kind = lsp.CIKConstant
+	case file.DeclSpecTypeFunc:
+		cand.kind = lsp.CIKFunction
+	case file.DeclSpecTypeImport:
+		cand.kind = lsp.CIKModule
+	case file.DeclSpecTypeType:
+		cand.kind = lsp.CIKClass
+		if spec, ok := decl.Node.(*ast.TypeSpec); ok {
+			cand.kind = typeExprKind(spec.Type)
+		}
+	}
+	return cand
+}
+
+// qualifiedType 其他包中声明的候选项，类型中的导出类型加上文件中引用这个包使用的名称
+func qualifiedType(typ func() string, pkg string) func() string {
+	if typ == nil {
+		return nil
+	}
+	return func() string { return file.QualifyType(typ(), pkg) }
+}
+
+func typeExprKind(expr ast.Expr) lsp.CompletionItemKind {
+	switch expr.(type) {
+	case *ast.StructType:
+		return lsp.CIKStruct
+	case *ast.InterfaceType:
+		return lsp.CIKInterface
+	}
+	return lsp.CIKClass
+}
+
+// packageFiles 同一个包中的其他文件，忽略构建约束不成立的文件，只在第一次调用时解析
+func (r *request) packageFiles() []*file.GoFile {
+	if r.siblings != nil {
+		return r.siblings
+	}
+	r.siblings = []*file.GoFile{}
+	dir := filepath.Dir(r.filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return r.siblings
+	}
+	isTest := strings.HasSuffix(r.filename, "_test.go")
+	for _, entry := range entries {
+		name := entry.Name()
+		filename := filepath.Join(dir, name)
+		if entry.IsDir() || filepath.Ext(name) != ".go" || filename == r.filename {
+			continue
+		}
+		if strings.HasSuffix(name, "_test.go") && !isTest {
+			continue
+		}
+		code, err := document.Read(file.PathToURI(filename))
+		if err != nil {
+			continue
+		}
+		gf, err := cache.ParseGoCode(filename, code)
+		if err != nil || gf.File.Name.Name != r.gf.File.Name.Name || !r.c.Config.Build.Match(gf.Constraint) {
+			continue
+		}
+		r.siblings = append(r.siblings, gf)
+	}
+	return r.siblings
+}
+
+// goPackage 当前文件和同一个包中的其他文件
+func (r *request) goPackage() *file.GoPackage {
+	return &file.GoPackage{Name: r.gf.File.Name.Name, Files: append([]*file.GoFile{r.gf}, r.packageFiles()...)}
+}
+
+// packageCandidates 同一个包中其他文件定义的包级别符号
+func (r *request) packageCandidates() []candidate {
+	var candidates []candidate
+	for _, gf := range r.packageFiles() {
+		for _, decl := range gf.Scope.Decls {
+			// import 只在声明它的文件中可见
+			if decl.Type == file.DeclSpecTypeImport || decl.Name == "_" {
+				continue
+			}
+			cand := r.declCandidate(gf, decl)
+			cand.scope = packageScore
+			candidates = append(candidates, cand)
+		}
+	}
+	return candidates
+}
+
+// importCandidates 导入包的导出成员，包已经完成类型检查时使用类型信息，否则查询索引
+func (r *request) importCandidates(spec file.ImportSpec) []candidate {
+	if pkg, ok := typecheck.Load(r.filename); ok {
+		for _, imported := range pkg.Types.Imports() {
+			// 导入失败时 go/types 使用一个空的包代替，回退到索引
+			if imported.Path() != spec.Path || imported.Scope().Len() == 0 {
+				continue
+			}
+			var candidates []candidate
+			qualifier := func(p *types.Package) string {
+				if p == imported {
+					return spec.Name
+				}
+				return p.Name()
+			}
+			scope := imported.Scope()
+			for _, name := range scope.Names() {
+				obj := scope.Lookup(name)
+				if !obj.Exported() {
+					continue
+				}
+				candidates = append(candidates, candidate{
+					label: name,
+					kind:  objectKind(obj),
+					typ:   func() string { return types.TypeString(obj.Type(), qualifier) },
+					resolve: func() (string, string) {
+						position := pkg.Position(obj)
+						return types.ObjectString(obj, types.RelativeTo(imported)), sourceDoc(position.Filename, position.Line, position.Column)
+					},
+				})
+			}
+			return candidates
+		}
+	}
+
+	return r.packageMembers(spec.Path, spec.PackageName())
+}
+
+// packageMembers 包的导出成员，先查询索引，索引中没有标准库时解析标准库的源码
+func (r *request) packageMembers(path, name string) []candidate {
+	// 按 import 路径确定索引中的包，不同路径的同名包不会混在一起
+	params := cache.IndexFindParams{PackagePath: &path, Workspace: &r.resolver.Workspace, Build: r.resolver.Build}
+	indexes, err := cache.FindIndex(params)
+	if err != nil {
+		indexes = nil
+	}
+	var candidates []candidate
+	for _, index := range indexes {
+		if !token.IsExported(index.KeyWorld) {
+			continue
+		}
+		cand := candidate{label: index.KeyWorld}
+		cand.resolve = func() (string, string) {
+			return cache.IndexString(index), sourceDoc(index.FilePath, index.JoinLine, index.JoinCol)
+		}
+		switch index.Type {
+		case model.IndexTypeVar:
+			cand.kind = lsp.CIKVariable
+			cand.typ = func() string { return file.QualifyType(index.Extra, name) }
+		case model.IndexTypeFunc:
+			cand.kind = lsp.CIKFunction
+			cand.typ = func() string { return file.QualifyType(index.Extra, name) }
+		case model.IndexTypeType:
+			cand.kind = lsp.CIKClass
+		default:
+			// 字段和方法不是包的成员
+			continue
+		}
+		candidates = append(candidates, cand)
+	}
+	if len(candidates) == 0 && file.IsStdImport(path) {
+		return r.stdMembers(path, name)
+	}
+	return candidates
+}
+
+func objectKind(obj types.Object) lsp.CompletionItemKind {
+	switch obj := obj.(type) {
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return lsp.CIKMethod
+		}
+		return lsp.CIKFunction
+	case *types.Var:
+		if obj.IsField() {
+			return lsp.CIKField
+		}
+		return lsp.CIKVariable
+	case *types.Const:
+		return lsp.CIKConstant
+	case *types.TypeName:
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			return lsp.CIKStruct
+		case *types.Interface:
+			return lsp.CIKInterface
+		}
+		return lsp.CIKClass
+	case *types.PkgName:
+		return lsp.CIKModule
+	}
+	return lsp.CIKText
+}
@@ -0,0 +1,141 @@
+package completion
+
+import (
+	"fmt"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/token"
+	"pkg.nimblebun.works/go-lsp"
+	"strings"
+)
+
+// fillFieldsLabel 一次填充全部剩余字段的候选项
+const fillFieldsLabel = "fill all fields"
+
+// compositeLitCandidates 结构体字面量中还没有设置的字段，选中时插入 Name: ，以及用零值填充全部剩余字段的候选项。
+// 字面量已经有不带字段名称的元素，或者类型不是 struct 时返回 false
+func (r *request) compositeLitCandidates(lit file.CompositeLit) ([]candidate, bool) {
+	if lit.Positional {
+		return nil, false
+	}
+	fields, lookup, ok := r.structFields(lit.Type)
+	if !ok {
+		return nil, false
+	}
+
+	set := make(map[string]bool, len(lit.Keys))
+	for _, key := range lit.Keys {
+		set[key] = true
+	}
+	var remaining []file.TypeInfoSpec
+	var candidates []candidate
+	for _, field := range fields {
+		if set[*field.Name] {
+			continue
+		}
+		remaining = append(remaining, field)
+		candidates = append(candidates, candidate{
+			label:  *field.Name,
+			kind:   lsp.CIKField,
+			insert: *field.Name + ": ",
+			scope:  highScore,
+			resolve: func() (string, string) {
+				detail := "field " + *field.Name + " " + field.Type
+				if field.Tag != "" {
+					detail += " `" + field.Tag + "`"
+				}
+				return detail, field.Comment
+			},
+		})
+	}
+	if len(remaining) == 0 {
+		return candidates, true
+	}
+
+	// 多行的字面量每个字段一行，否则写在同一行
+	sep := ", "
+	if lit.Multiline {
+		sep = ",\n" + lit.Indent
+	}
+	names := make([]string, 0, len(remaining))
+	values := make([]string, 0, len(remaining))
+	for i, field := range remaining {
+		value := file.ZeroValue(field.Type, lookup)
+		if r.c.Config.SnippetSupport {
+			value = fmt.Sprintf("${%d:%s}", i+1, escapeSnippet(value))
+		}
+		names = append(names, *field.Name)
+		values = append(values, *field.Name+": "+value)
+	}
+	fill := candidate{label: fillFieldsLabel, kind: lsp.CIKSnippet, detail: strings.Join(names, ", "), scope: highScore}
+	text := strings.Join(values, sep)
+	if lit.Multiline {
+		text += ","
+	}
+	if r.c.Config.SnippetSupport {
+		fill.snippet = text
+	} else {
+		fill.insert = text
+	}
+	return append([]candidate{fill}, candidates...), true
+}
+
+// structFields 字面量类型的字段，包括嵌入字段。其他包中的类型先查询索引，再解析标准库的源码，
+// 只返回导出的字段。字段类型和 lookup 中的类型都使用当前文件引用其他包的名称
+func (r *request) structFields(typ string) ([]file.TypeInfoSpec, func(name string) (*file.TypeSpec, bool), bool) {
+	typ = strings.TrimPrefix(typ, "*")
+	// 泛型类型的实例 Pair[int]，字段属于 Pair
+	if i := strings.Index(typ, "["); i > 0 {
+		typ = typ[:i]
+	}
+	if typ == "" || !isTypeName(typ) {
+		return nil, nil, false
+	}
+	gopkg := r.goPackage()
+	lookup := func(name string) (*file.TypeSpec, bool) {
+		return r.lookupType(gopkg, name)
+	}
+	t, ok := lookup(typ)
+	if !ok || !strings.HasPrefix(t.Type, "struct") && len(t.Fields) == 0 {
+		return nil, nil, false
+	}
+	return t.Fields, lookup, true
+}
+
+// lookupType 查找类型声明，name 为 T 时在当前包中查找，为 pkg.T 时在文件导入的包中查找
+func (r *request) lookupType(gopkg *file.GoPackage, name string) (*file.TypeSpec, bool) {
+	alias, typeName, ok := strings.Cut(name, ".")
+	if !ok {
+		return gopkg.LookupType(name)
+	}
+	spec, ok := r.gf.ImportByName(alias)
+	if !ok {
+		return nil, false
+	}
+	t, _, ok := r.resolver.LookupType(spec.PackageName(), typeName)
+	if !ok && file.IsStdImport(spec.Path) {
+		t, ok = r.stdPackage(spec.Path, spec.PackageName()).LookupType(typeName)
+	}
+	if !ok {
+		return nil, false
+	}
+
+	// 索引中的嵌入字段单独保存，不在 Fields 中
+	qualified := *t
+	qualified.Type = file.QualifyType(t.Type, alias)
+	qualified.Fields = nil
+	seen := make(map[string]bool)
+	for _, field := range t.Fields {
+		seen[*field.Name] = true
+		if token.IsExported(*field.Name) {
+			field.Type = file.QualifyType(field.Type, alias)
+			qualified.Fields = append(qualified.Fields, field)
+		}
+	}
+	for _, embed := range t.Embeds {
+		if !seen[embed.Name] && token.IsExported(embed.Name) {
+			embedName := embed.Name
+			qualified.Fields = append(qualified.Fields, file.TypeInfoSpec{Name: &embedName, Type: file.QualifyType(embed.Type, alias)})
+		}
+	}
+	return &qualified, true
+}
@@ -0,0 +1,84 @@
+package completion
+
+import (
+	"pkg.nimblebun.works/go-lsp"
+	"testing"
+)
+
+func TestCompositeLitCompletion(t *testing.T) {
+	// 同一个包的另一个文件
+	other := "package m\n\ntype User struct {\n\tName string\n\tAge  int\n\tTags []string\n}\n"
+	tests := []struct {
+		name    string
+		code    string
+		snippet bool
+		want    []string
+		exclude []string
+		fill    string // fillFieldsLabel 插入的文本，为空时没有这一项
+	}{
+		{
+			name:    "unset fields",
+			code:    "package m\n\nfunc f() {\n\t_ = User{Name: \"a\", " + cursorMark + "}\n}\n",
+			want:    []string{"Age", "Tags"},
+			exclude: []string{"Name", "f"},
+			fill:    "Age: 0, Tags: nil",
+		},
+		{
+			name:    "empty literal",
+			code:    "package m\n\nfunc f() {\n\t_ = User{" + cursorMark + "}\n}\n",
+			snippet: true,
+			// 还没有元素时也可以按顺序写值
+			want: []string{"Name", "Age", "Tags", "f"},
+			fill: `Name: ${1:""}, Age: ${2:0}, Tags: ${3:nil}`,
+		},
+		{
+			name: "multiline",
+			code: "package m\n\nfunc f() {\n\t_ = User{\n\t\tName: \"a\",\n\t\t" + cursorMark + "\n\t}\n}\n",
+			want: []string{"Age", "Tags"},
+			fill: "Age: 0,\n\t\tTags: nil,",
+		},
+		{
+			name: "std type",
+			code: "package m\n\nimport \"image\"\n\nfunc f() {\n\t_ = image.Point{" + cursorMark + "}\n}\n",
+			want: []string{"X", "Y"},
+			fill: "X: 0, Y: 0",
+		},
+		{
+			name:    "all set",
+			code:    "package m\n\nfunc f() {\n\t_ = User{Name: \"a\", Age: 1, Tags: nil, " + cursorMark + "}\n}\n",
+			exclude: []string{"Name", "f"},
+		},
+		{
+			name:    "positional",
+			code:    "package m\n\nfunc f() {\n\t_ = User{\"a\", " + cursorMark + "}\n}\n",
+			want:    []string{"f"},
+			exclude: []string{"Age"},
+		},
+	}
+	dir := writeModule(t, map[string]string{"other.go": other})
+	for _, tt := range tests {
+		list := complete(t, testService(dir, tt.snippet), dir, "m.go", tt.code)
+		got := labels(list)
+		for _, label := range tt.want {
+			if !got[label] {
+				t.Errorf("%s: missing %s", tt.name, label)
+			}
+		}
+		for _, label := range tt.exclude {
+			if got[label] {
+				t.Errorf("%s: unexpected %s", tt.name, label)
+			}
+		}
+		item, ok := findItem(list, fillFieldsLabel)
+		switch {
+		case tt.fill == "" && ok:
+			t.Errorf("%s: unexpected %s: %q", tt.name, fillFieldsLabel, item.InsertText)
+		case tt.fill != "" && !ok:
+			t.Errorf("%s: missing %s", tt.name, fillFieldsLabel)
+		case ok && item.InsertText != tt.fill:
+			t.Errorf("%s: fill = %q, want %q", tt.name, item.InsertText, tt.fill)
+		case ok && tt.snippet != (item.InsertTextFormat == lsp.ITFSnippet):
+			t.Errorf("%s: fill format = %v", tt.name, item.InsertTextFormat)
+		}
+	}
+}
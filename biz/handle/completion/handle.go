@@ -2,35 +2,188 @@ package completion
 
 import (
 	"context"
+	"fmt"
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/token"
 	"pkg.nimblebun.works/go-lsp"
+	"strings"
 )
 
-func Handle(ctx context.Context, params *lsp.CompletionParams) (interface{}, error) {
+// request 一次补全请求的上下文
+type request struct {
+	c        *engine.LspService
+	uri      string
+	filename string
+	gf       *file.GoFile
+	pos      token.Pos
+	prefix   string        // 光标前正在输入的标识符
+	word     file.Position // prefix 开始的位置
+	selector string        // prefix 前面 x. 中 x 的文本
+	dot      bool          // prefix 前面是不是 .
+	resolver cache.IndexResolver
+	siblings []*file.GoFile // 同一个包中的其他文件
+	mod      *moduleInfo
+	context  file.CursorContext
+	// importStart 光标在 import 路径中时为路径开始的位置，这时 prefix 是已经输入的路径
+	importStart *file.Position
+}
+
+// candidate 补全候选项
+type candidate struct {
+	label   string
+	kind    lsp.CompletionItemKind
+	detail  string         // 简短的描述，有 resolve 时为空，在 resolve 中计算
+	insert  string         // 插入的文本，为空时插入 label
+	snippet string         // 不为空时按 snippet 格式插入
+	edit    *file.TextEdit // 选中时额外的修改，比如添加 import
+	replace *file.TextEdit // 不为空时用这个修改代替插入，可以替换光标前面不是标识符的文本
+	filter  string         // 客户端过滤使用的文本，为空时是 label
+	// resolve 在 completionItem/resolve 时计算完整的签名和文档注释，为 nil 时没有更多信息
+	resolve func() (detail string, doc string)
+	scope   float64       // 作用域的相关度，为 0 时是 stdScore
+	typ     func() string // 候选项的类型，函数为函数签名，用于和光标处需要的类型比较
+}
+
+func Handle(ctx context.Context, c *engine.LspService, params *lsp.CompletionParams) (interface{}, error) {
+	req, err := newRequest(c, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req.recordTyped()
+	ranked := req.rank(req.candidates())
+	// 截断后继续输入时客户端需要重新请求，否则在这个结果里过滤即可
+	incomplete := len(ranked) > maxCompletionItems
+	if incomplete {
+		ranked = ranked[:maxCompletionItems]
+	}
+	id := saveResolves(ranked)
+	req.saveWord(ranked)
+	items := make([]lsp.CompletionItem, 0, len(ranked))
+	for i, cand := range ranked {
+		item := buildCompletionItem(req.gf, cand.candidate)
+		item.SortText = fmt.Sprintf("%04d", i)
+		item.FilterText = cand.label
+		if cand.filter != "" {
+			item.FilterText = cand.filter
+		}
+		if cand.resolve != nil {
+			item.Data = resolveData{ID: id, URI: params.TextDocument.URI, Position: params.Position}
+		}
+		items = append(items, item)
+	}
 	return lsp.CompletionList{
-		IsIncomplete: true,
-		Items: []lsp.CompletionItem{
-			buildCompletionItem(IMPORT, lsp.CIKKeyword),
-			buildCompletionItem(IF, lsp.CIKKeyword),
-			buildCompletionItem(CASE, lsp.CIKKeyword),
-			buildCompletionItem(DEFAULT, lsp.CIKKeyword),
-			buildCompletionItem(FUNC, lsp.CIKKeyword),
-			buildCompletionItem(SWITCH, lsp.CIKKeyword),
-		},
+		IsIncomplete: incomplete,
+		Items:        items,
+	}, nil
+}
+
+// candidates 光标处的全部候选项，同名的候选项只有第一个有效，没有按 prefix 过滤
+func (r *request) candidates() []candidate {
+	switch {
+	case r.importStart != nil:
+		return r.importPathCandidates()
+	case r.context.Kind == file.ContextComment || r.context.Kind == file.ContextString:
+		// 注释和字符串中不补全
+		return nil
+	case r.context.Kind == file.ContextName && !r.dot:
+		// 正在输入新声明的名称
+		return nil
+	case r.dot:
+		return r.selectorCandidates()
+	}
+	// 结构体字面量中只能写字段名称，还没有元素时也可以按顺序写值
+	if lit, ok := r.gf.CompositeLitAt(r.pos); ok {
+		if fields, ok := r.compositeLitCandidates(lit); ok {
+			if len(lit.Keys) > 0 {
+				return fields
+			}
+			return append(fields, r.identCandidates()...)
+		}
+	}
+	return r.identCandidates()
+}
+
+func newRequest(c *engine.LspService, params *lsp.CompletionParams) (*request, error) {
+	uri := string(params.TextDocument.URI)
+	filename := file.URIToPath(uri)
+	code, err := document.Read(uri)
+	if err != nil {
+		return nil, err
+	}
+	// 输入过程中文件通常有语法错误，使用部分恢复的 ast
+	gf, err := cache.ParseGoCode(filename, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// lsp 的列按 utf-16 编码单元计算，文件中按字节计算
+	line := params.Position.Line
+	position := file.Position{Filename: filename, Line: line, Column: gf.ByteColumn(line, params.Position.Character)}
+	prefix, selector, dot := gf.IdentPrefix(position)
+	word := file.Position{Filename: filename, Line: line, Column: position.Column - len(prefix)}
+	pos := gf.TokenPos(position)
+	// import 路径按整个已经输入的路径过滤
+	var importStart *file.Position
+	if path, start, ok := gf.ImportPathAt(pos); ok {
+		prefix, selector, dot = path, "", false
+		importStart = &start
+	}
+	return &request{
+		c:           c,
+		uri:         uri,
+		filename:    filename,
+		gf:          gf,
+		pos:         pos,
+		prefix:      prefix,
+		word:        word,
+		selector:    selector,
+		dot:         dot,
+		context:     gf.CursorContext(position),
+		resolver:    cache.IndexResolver{Workspace: c.Config.Workspace(filename), Build: &c.Config.Build, File: gf},
+		importStart: importStart,
 	}, nil
 }
 
-func buildCompletionItem(label string, kind lsp.CompletionItemKind) lsp.CompletionItem {
-	return lsp.CompletionItem{
-		Label:      label,
-		Kind:       kind,
-		Detail:     label,
-		InsertText: label,
+// matchPrefix 不区分大小写的前缀匹配
+func matchPrefix(label, prefix string) bool {
+	return len(label) >= len(prefix) && strings.EqualFold(label[:len(prefix)], prefix)
+}
+
+func buildCompletionItem(gf *file.GoFile, cand candidate) lsp.CompletionItem {
+	item := lsp.CompletionItem{
+		Label:      cand.label,
+		Kind:       cand.kind,
+		Detail:     cand.detail,
+		InsertText: cand.label,
 		Tags:       []lsp.CompletionItemTag{},
 	}
+	if cand.insert != "" {
+		item.InsertText = cand.insert
+	}
+	if cand.snippet != "" {
+		item.InsertText = cand.snippet
+		item.InsertTextFormat = lsp.ITFSnippet
+	}
+	if cand.replace != nil {
+		item.TextEdit = lspTextEdit(gf, cand.replace)
+	}
+	if cand.edit != nil {
+		item.AdditionalTextEdits = []lsp.TextEdit{*lspTextEdit(gf, cand.edit)}
+	}
+	return item
 }
 
-const (
-	lowScore  float64 = 0.01
-	stdScore  float64 = 1.0
-	highScore float64 = 100.0
-)
+// lspTextEdit 将按字节计算列的修改转换为 lsp 的修改
+func lspTextEdit(gf *file.GoFile, edit *file.TextEdit) *lsp.TextEdit {
+	return &lsp.TextEdit{
+		Range: lsp.Range{
+			Start: lsp.Position{Line: edit.Start.Line, Character: gf.UTF16Column(edit.Start.Line, edit.Start.Column)},
+			End:   lsp.Position{Line: edit.End.Line, Character: gf.UTF16Column(edit.End.Line, edit.End.Column)},
+		},
+		NewText: edit.NewText,
+	}
+}
@@ -0,0 +1,277 @@
+package completion
+
+import (
+	"context"
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/biz/flags"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"os"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// cursorMark 测试源码中光标的位置，不属于源码
+const cursorMark = "‸"
+
+func TestMain(m *testing.M) {
+	// 索引和用户的 snippets.json 都放在临时的配置目录中，不影响用户的缓存
+	dir, err := os.MkdirTemp("", "completion")
+	if err != nil {
+		panic(err)
+	}
+	flags.SERVICE_CONFIG_DIR = dir
+	cache.Init()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testService 工作区为 dir 的服务，snippet 表示客户端是否支持 snippet
+func testService(dir string, snippet bool) *engine.LspService {
+	return &engine.LspService{Config: engine.Config{
+		WorkFolds:       []string{dir},
+		ServerConfigDir: flags.SERVICE_CONFIG_DIR,
+		Build:           file.DefaultBuildConfig(),
+		SnippetSupport:  snippet,
+	}}
+}
+
+// writeModule 在临时目录中写入 module example.com/m 和 files，返回目录，files 是相对路径 -> 源码
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.22\n"
+	for name, code := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// cursorParams 去掉 code 中的光标标记，返回源码和 lsp 的位置，列按 utf-16 编码单元计算
+func cursorParams(t *testing.T, uri, code string) (string, *lsp.CompletionParams) {
+	t.Helper()
+	i := strings.Index(code, cursorMark)
+	if i < 0 {
+		t.Fatalf("no cursor in %q", code)
+	}
+	before := code[:i]
+	line := strings.Count(before, "\n")
+	column := before[strings.LastIndex(before, "\n")+1:]
+	return before + code[i+len(cursorMark):], &lsp.CompletionParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: lsp.DocumentURI(uri)},
+			Position:     lsp.Position{Line: line, Character: len(utf16.Encode([]rune(column)))},
+		},
+	}
+}
+
+// complete 在 dir 中 name 文件的光标处请求补全，文件内容是编辑器中还没有保存的 code
+func complete(t *testing.T, c *engine.LspService, dir, name, code string) lsp.CompletionList {
+	t.Helper()
+	uri := file.PathToURI(filepath.Join(dir, name))
+	code, params := cursorParams(t, uri, code)
+	document.Set(uri, []byte(code))
+	t.Cleanup(func() { document.Delete(uri) })
+
+	result, err := Handle(context.Background(), c, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.(lsp.CompletionList)
+}
+
+func findItem(list lsp.CompletionList, label string) (lsp.CompletionItem, bool) {
+	for _, item := range list.Items {
+		if item.Label == label {
+			return item, true
+		}
+	}
+	return lsp.CompletionItem{}, false
+}
+
+// 光标前面有多字节字符时，请求的位置和返回的修改都按 utf-16 编码单元计算
+func TestCompletionUTF16(t *testing.T) {
+	code := "package m\n\nfunc f(s string) {\n\t_ = \"中😀\"; s.len" + cursorMark + "\n}\n"
+	dir := writeModule(t, map[string]string{})
+	list := complete(t, testService(dir, false), dir, "m.go", code)
+
+	item, ok := findItem(list, "len")
+	if !ok || item.TextEdit == nil {
+		t.Fatalf("postfix len not found in %d items", len(list.Items))
+	}
+	// \t_ = "中😀"; 占 12 个编码单元(按字节是 16 个)，s 从第 12 列开始
+	want := lsp.Range{Start: lsp.Position{Line: 3, Character: 12}, End: lsp.Position{Line: 3, Character: 17}}
+	if item.TextEdit.Range != want || item.TextEdit.NewText != "len(s)" {
+		t.Errorf("len edit = %+v %q, want %+v len(s)", item.TextEdit.Range, item.TextEdit.NewText, want)
+	}
+}
+
+func labels(list lsp.CompletionList) map[string]bool {
+	got := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		got[item.Label] = true
+	}
+	return got
+}
+
+func TestCompletionCandidates(t *testing.T) {
+	// 同一个包的另一个文件
+	other := "package m\n\nvar Shared int\n\nfunc helper() {}\n"
+	tests := []struct {
+		name    string
+		code    string
+		want    []string
+		exclude []string
+	}{
+		{
+			name:    "locals and package",
+			code:    "package m\n\nfunc f(param int) {\n\tlocal := 1\n\t" + cursorMark + "\n\tlater := 2\n}\n",
+			want:    []string{"param", "local", "f", "Shared", "helper", "len", "string", "for"},
+			exclude: []string{"later"},
+		},
+		{
+			name:    "prefix",
+			code:    "package m\n\nfunc f(param int) {\n\tpa" + cursorMark + "\n}\n",
+			want:    []string{"param", "panic"},
+			exclude: []string{"Shared", "helper"},
+		},
+		{
+			name: "comment",
+			code: "package m\n\nfunc f(param int) {\n\t// pa" + cursorMark + "\n}\n",
+		},
+		{
+			name: "string",
+			code: "package m\n\nfunc f(param int) {\n\t_ = \"pa" + cursorMark + "\"\n}\n",
+		},
+		{
+			name: "new name",
+			code: "package m\n\nvar pa" + cursorMark + " = 1\n",
+		},
+		{
+			name:    "top level",
+			code:    "package m\n\n" + cursorMark + "\n",
+			want:    []string{"func", "type", "var"},
+			exclude: []string{"Shared", "len"},
+		},
+	}
+	dir := writeModule(t, map[string]string{"other.go": other})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		got := labels(complete(t, c, dir, "m.go", tt.code))
+		if tt.want == nil && len(got) != 0 {
+			t.Errorf("%s: got %d items, want none", tt.name, len(got))
+		}
+		for _, label := range tt.want {
+			if !got[label] {
+				t.Errorf("%s: missing %s", tt.name, label)
+			}
+		}
+		for _, label := range tt.exclude {
+			if got[label] {
+				t.Errorf("%s: unexpected %s", tt.name, label)
+			}
+		}
+	}
+}
+
+// keywordLabels 补全结果中的关键字
+func keywordLabels(list lsp.CompletionList) map[string]bool {
+	got := make(map[string]bool)
+	for _, item := range list.Items {
+		if item.Kind == lsp.CIKKeyword {
+			got[item.Label] = true
+		}
+	}
+	return got
+}
+
+func TestCompletionKeywords(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    []string
+		exclude []string
+	}{
+		{
+			name:    "statement",
+			code:    "package m\n\nfunc f() {\n\t" + cursorMark + "\n}\n",
+			want:    []string{"for", "if", "return", "defer", "go", "var"},
+			exclude: []string{"break", "continue", "fallthrough", "else", "import", "package"},
+		},
+		{
+			name: "keyword prefix",
+			code: "package m\n\nfunc f() {\n\tfor {\n\t\tif" + cursorMark + "\n\t}\n}\n",
+			want: []string{"if"},
+		},
+		{
+			name: "loop",
+			code: "package m\n\nfunc f() {\n\tfor {\n\t\t" + cursorMark + "\n\t}\n}\n",
+			want: []string{"break", "continue", "if"},
+		},
+		{
+			name: "after if",
+			code: "package m\n\nfunc f(ok bool) {\n\tif ok {\n\t} " + cursorMark + "\n}\n",
+			want: []string{"else"},
+			// 只能写 else
+			exclude: []string{"if", "for", "return"},
+		},
+		{
+			name:    "case",
+			code:    "package m\n\nfunc f(x int) {\n\tswitch x {\n\tcase 1:\n\t\t" + cursorMark + "\n\tcase 2:\n\t}\n}\n",
+			want:    []string{"break", "fallthrough"},
+			exclude: []string{"continue"},
+		},
+		{
+			name:    "top level",
+			code:    "package m\n\n" + cursorMark + "\n",
+			want:    []string{"func", "type", "var", "const", "import"},
+			exclude: []string{"for", "return", "package"},
+		},
+		{
+			name:    "after decl",
+			code:    "package m\n\nvar x int\n\n" + cursorMark + "\n",
+			want:    []string{"func"},
+			exclude: []string{"import"},
+		},
+		{
+			name:    "file start",
+			code:    cursorMark,
+			want:    []string{"package"},
+			exclude: []string{"func"},
+		},
+		{
+			name: "func name",
+			code: "package m\n\nfunc ne" + cursorMark + "() {}\n",
+		},
+	}
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		list := complete(t, c, dir, "m.go", tt.code)
+		got := keywordLabels(list)
+		if tt.want == nil && len(list.Items) != 0 {
+			t.Errorf("%s: got %d items, want none", tt.name, len(list.Items))
+		}
+		for _, label := range tt.want {
+			if !got[label] {
+				t.Errorf("%s: missing keyword %s in %v", tt.name, label, got)
+			}
+		}
+		for _, label := range tt.exclude {
+			if got[label] {
+				t.Errorf("%s: unexpected keyword %s", tt.name, label)
+			}
+		}
+	}
+}
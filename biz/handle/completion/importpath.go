@@ -0,0 +1,163 @@
+package completion
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/flags"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"golang.org/x/mod/module"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"strings"
+)
+
+// importPathCandidates import 路径字符串中的候选项，来自标准库、依赖的 module 和当前 module 中的包。
+// 每次只补全到下一个 /，还有更深的包时候选项以 / 结尾，已经导入的包不再出现
+func (r *request) importPathCandidates() []candidate {
+	typed := r.prefix
+	dir := typed[:strings.LastIndex(typed, "/")+1]
+	self := r.module().packagePath(r.filename)
+
+	cursor := *r.importStart
+	cursor.Column += len(typed)
+	var candidates []candidate
+	seen := make(map[string]bool)
+	for path, version := range r.importPaths(dir) {
+		if !strings.HasPrefix(path, dir) || path == self || !importable(path, self) {
+			continue
+		}
+		if len(r.gf.ImportsOf(path)) > 0 && path != typed {
+			continue
+		}
+		segment, _, deeper := strings.Cut(path[len(dir):], "/")
+		label, kind := dir+segment, lsp.CIKModule
+		if deeper {
+			label, kind = label+"/", lsp.CIKFolder
+		}
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		candidates = append(candidates, candidate{
+			label:   label,
+			kind:    kind,
+			detail:  version,
+			replace: &file.TextEdit{Start: *r.importStart, End: cursor, NewText: label},
+		})
+	}
+	return candidates
+}
+
+// importPaths 以 dir 开头的可以导入的包路径 -> 所属 module 的版本，标准库的版本为 go 的版本，当前 module 中的包没有版本
+func (r *request) importPaths(dir string) map[string]string {
+	paths := make(map[string]string)
+	goVersion := file.StdVersion()
+	for _, list := range file.StdPackages() {
+		for _, path := range list {
+			paths[path] = goVersion
+		}
+	}
+
+	// 依赖: go.mod 中的 require
+	mod := r.module()
+	for path, version := range mod.versions {
+		switch {
+		case strings.HasPrefix(path, dir):
+			// module 本身就足以补全下一段
+			paths[path] = version
+		case strings.HasPrefix(dir, path+"/"):
+			// 已经输入到 module 内部，列出 module 中 dir 下一层的包
+			for _, pkg := range modulePackages(path, moduleCacheDir(path, version), dir) {
+				paths[pkg] = version
+			}
+		}
+	}
+	// 索引中的包，包括没有下载到 module 缓存的依赖
+	if pkgs, err := cache.SearchPackagePaths(dir, r.resolver.Workspace); err == nil {
+		for _, pkg := range pkgs {
+			if _, ok := paths[pkg.Name]; !ok {
+				paths[pkg.Name] = pkg.Version
+			}
+		}
+	}
+
+	switch {
+	case mod.path == "" || mod.dir == "":
+	case strings.HasPrefix(mod.path, dir):
+		if file.HasGoFiles(mod.dir) {
+			paths[mod.path] = ""
+		}
+		if hasPackageDir(mod.dir) {
+			paths[mod.path+"/"] = ""
+		}
+	case strings.HasPrefix(dir, mod.path+"/"):
+		for _, pkg := range modulePackages(mod.path, mod.dir, dir) {
+			paths[pkg] = ""
+		}
+	}
+	return paths
+}
+
+// moduleCacheDir 依赖在 module 缓存中的目录，路径中的大写字母按 go 的规则转义
+func moduleCacheDir(path, version string) string {
+	escaped, err := module.EscapePath(path)
+	if err != nil {
+		return ""
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(flags.GetGoModCache(), escaped+"@"+escapedVersion)
+}
+
+// modulePackages module 中 dir 下一层的包路径，dir 是 module 内部以 / 结尾的路径。
+// 每次只补全到下一个 /，所以只读取一层目录，还有更深的包时路径以 / 结尾
+func modulePackages(modPath, root, dir string) []string {
+	if root == "" || !strings.HasPrefix(dir, modPath+"/") {
+		return nil
+	}
+	base := filepath.Join(root, filepath.FromSlash(dir[len(modPath)+1:]))
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(base, entry.Name())
+		if !packageDir(path, entry) {
+			continue
+		}
+		if file.HasGoFiles(path) {
+			paths = append(paths, dir+entry.Name())
+		}
+		if hasPackageDir(path) {
+			paths = append(paths, dir+entry.Name()+"/")
+		}
+	}
+	return paths
+}
+
+// hasPackageDir 目录下是否有可能包含包的子目录
+func hasPackageDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if packageDir(filepath.Join(dir, entry.Name()), entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// packageDir 是否是可能包含当前 module 中的包的目录，跳过 testdata、vendor 和嵌套的 module
+func packageDir(path string, entry fs.DirEntry) bool {
+	name := entry.Name()
+	if !entry.IsDir() || name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return false
+	}
+	return !file.Exists(filepath.Join(path, "go.mod"))
+}
@@ -0,0 +1,116 @@
+package completion
+
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestModulePackages(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"a/a.go",
+		"a/deep/d.go",
+		"b/sub/s.go",    // b 中没有 go 文件，只能继续补全
+		"t/t_test.go",   // 只有测试文件
+		"testdata/x.go", // 跳过 testdata
+		"vendor/v/v.go", // 跳过 vendor
+		"nested/go.mod", // 嵌套的 module 不属于这个 module
+		"nested/n.go",
+	} {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		root string
+		dir  string
+		want []string
+	}{
+		{root, "example.com/m/", []string{"example.com/m/a", "example.com/m/a/", "example.com/m/b/"}},
+		{root, "example.com/m/a/", []string{"example.com/m/a/deep"}},
+		{root, "example.com/m/b/sub/", nil},
+		{root, "example.com/other/", nil},
+		{"", "example.com/m/", nil},
+	}
+	for _, tt := range tests {
+		got := modulePackages("example.com/m", tt.root, tt.dir)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("modulePackages(%q, %q) = %v, want %v", tt.root, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestImportPathCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    []string
+		exclude []string
+	}{
+		{
+			name:    "std",
+			code:    "package m\n\nimport \"fm" + cursorMark + "\"\n",
+			want:    []string{"fmt"},
+			exclude: []string{"net/http"},
+		},
+		{
+			name:    "next segment",
+			code:    "package m\n\nimport \"net/ht" + cursorMark + "\"\n",
+			want:    []string{"net/http", "net/http/"},
+			exclude: []string{"net/http/httptest", "net/url"},
+		},
+		{
+			name:    "module packages",
+			code:    "package m\n\nimport \"example.com/m/" + cursorMark + "\"\n",
+			want:    []string{"example.com/m/a", "example.com/m/a/", "example.com/m/internal/"},
+			exclude: []string{"example.com/m/a/deep", "example.com/m/"},
+		},
+		{
+			name:    "imported",
+			code:    "package m\n\nimport (\n\t\"fmt\"\n\t\"fm" + cursorMark + "\"\n)\n",
+			exclude: []string{"fmt"},
+		},
+	}
+	dir := writeModule(t, map[string]string{
+		"a/a.go":          "package a\n",
+		"a/deep/deep.go":  "package deep\n",
+		"internal/i/i.go": "package i\n",
+	})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		list := complete(t, c, dir, "m.go", tt.code)
+		got := labels(list)
+		for _, label := range tt.want {
+			if !got[label] {
+				t.Errorf("%s: missing %s", tt.name, label)
+			}
+		}
+		for _, label := range tt.exclude {
+			if got[label] {
+				t.Errorf("%s: unexpected %s", tt.name, label)
+			}
+		}
+	}
+
+	// 选中时替换整个已经输入的路径，标准库的版本是本机 go 的版本
+	list := complete(t, c, dir, "m.go", "package m\n\nimport \"net/ht"+cursorMark+"\"\n")
+	item, ok := findItem(list, "net/http")
+	if !ok || item.TextEdit == nil {
+		t.Fatal("net/http has no text edit")
+	}
+	if item.TextEdit.NewText != "net/http" || item.TextEdit.Range.Start.Character != 8 || item.TextEdit.Range.End.Character != 14 {
+		t.Errorf("net/http edit = %+v", item.TextEdit)
+	}
+	if item.Detail != file.StdVersion() {
+		t.Errorf("net/http detail = %q, want %q", item.Detail, file.StdVersion())
+	}
+}
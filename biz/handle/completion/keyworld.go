@@ -1,5 +1,9 @@
 package completion
 
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+)
+
 const (
 	VAR         = "var"
 	IF          = "if"
@@ -27,3 +31,52 @@ const (
 	STRUCT      = "struct"
 	MAP         = "map"
 )
+
+// 语句开头都可以使用的关键字
+var statementKeywords = []string{CONST, DEFER, FOR, FUNC, GO, GOTO, IF, RETURN, SELECT, SWITCH, TYPE, VAR}
+
+// 类型和表达式中可以出现的关键字
+var typeKeywords = []string{CHAN, FUNC, INTERFACE, MAP, STRUCT}
+
+// contextKeywords 返回在光标处的语法上下文中合法的关键字
+func contextKeywords(ctx file.CursorContext) []string {
+	switch ctx.Kind {
+	case file.ContextFileStart:
+		return []string{PACKAGE}
+	case file.ContextTopLevel:
+		if ctx.ImportsOnly {
+			return []string{CONST, FUNC, IMPORT, TYPE, VAR}
+		}
+		return []string{CONST, FUNC, TYPE, VAR}
+	case file.ContextSwitchBody:
+		return []string{CASE, DEFAULT}
+	case file.ContextStatement:
+		// if 的 } 后面同一行只能写 else
+		if ctx.AfterIf {
+			return []string{ELSE}
+		}
+		keywords := append([]string{}, statementKeywords...)
+		if ctx.InLoop || ctx.InSwitch || ctx.InSelect {
+			keywords = append(keywords, BREAK)
+		}
+		if ctx.InLoop {
+			keywords = append(keywords, CONTINUE)
+		}
+		// 子句中的语句后面可以开始下一个子句
+		if ctx.InSwitch || ctx.InSelect {
+			keywords = append(keywords, CASE, DEFAULT)
+		}
+		if ctx.CaseEnd {
+			keywords = append(keywords, FALLTHROUGH)
+		}
+		return keywords
+	case file.ContextType:
+		return typeKeywords
+	case file.ContextExpression:
+		if ctx.ForHeader {
+			return append([]string{RANGE}, typeKeywords...)
+		}
+		return typeKeywords
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package completion
+
+import (
+	"fmt"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/ast"
+	"pkg.nimblebun.works/go-lsp"
+	"regexp"
+	"strings"
+)
+
+// maxUnderlyingDepth 查找底层类型时最多展开的类型声明层数，防止循环定义
+const maxUnderlyingDepth = 8
+
+// postfixExpr 后缀补全 x.name 中的 x
+type postfixExpr struct {
+	text       string // x 的源码，已经按 snippet 转义，不支持 snippet 时由 snippetPlainText 还原
+	typ        string // x 的类型
+	underlying string // x 的底层类型
+	statement  bool   // x 在语句的开头，可以替换为语句
+}
+
+// postfixTemplate 后缀补全的模板，x.label 整个替换为 expand 生成的代码
+type postfixTemplate struct {
+	label     string
+	detail    string // %s 为 x 的源码
+	statement bool   // 生成的是语句
+	// expand 使用 snippet 语法生成代码，x 的类型不适用时返回空字符串
+	expand func(r *request, x postfixExpr) string
+}
+
+var postfixTemplates = []postfixTemplate{
+	{label: "return", detail: "return %s", statement: true, expand: (*request).postfixReturn},
+	{label: "ifnil", detail: "if %s == nil {}", statement: true, expand: func(r *request, x postfixExpr) string {
+		if !r.nilable(x.typ) {
+			return ""
+		}
+		return "if " + x.text + " == nil {\n\t$0\n}"
+	}},
+	{label: "ifnotnil", detail: "if %s != nil {}", statement: true, expand: func(r *request, x postfixExpr) string {
+		if !r.nilable(x.typ) {
+			return ""
+		}
+		return "if " + x.text + " != nil {\n\t$0\n}"
+	}},
+	{label: "range", detail: "for k, v := range %s {}", statement: true, expand: func(r *request, x postfixExpr) string {
+		switch rangeKind(x.underlying) {
+		case "slice":
+			return "for ${1:i}, ${2:v} := range " + x.text + " {\n\t$0\n}"
+		case "string":
+			return "for ${1:i}, ${2:r} := range " + x.text + " {\n\t$0\n}"
+		case "map":
+			return "for ${1:k}, ${2:v} := range " + x.text + " {\n\t$0\n}"
+		case "chan":
+			return "for ${1:v} := range " + x.text + " {\n\t$0\n}"
+		}
+		return ""
+	}},
+	{label: "for", detail: "for k := range %s {}", statement: true, expand: func(r *request, x postfixExpr) string {
+		switch rangeKind(x.underlying) {
+		case "slice", "string":
+			return "for ${1:i} := range " + x.text + " {\n\t$0\n}"
+		case "map":
+			return "for ${1:k} := range " + x.text + " {\n\t$0\n}"
+		case "chan":
+			return "for ${1:v} := range " + x.text + " {\n\t$0\n}"
+		}
+		return ""
+	}},
+	{label: "keys", detail: "keys := make([]K, 0, len(%s))", statement: true, expand: func(r *request, x postfixExpr) string {
+		key, _ := file.RangeTypes(x.underlying)
+		if rangeKind(x.underlying) != "map" || key == "" {
+			return ""
+		}
+		return "${1:keys} := make([]" + escapeSnippet(key) + ", 0, len(" + x.text + "))\n" +
+			"for ${2:k} := range " + x.text + " {\n\t$1 = append($1, $2)\n}$0"
+	}},
+	{label: "print", detail: "fmt.Println(%s)", statement: true, expand: func(r *request, x postfixExpr) string {
+		prefix, _, ok := r.fmtImport()
+		if !ok {
+			return ""
+		}
+		return prefix + "Println(" + x.text + ")$0"
+	}},
+	{label: "len", detail: "len(%s)", expand: func(r *request, x postfixExpr) string {
+		if rangeKind(x.underlying) == "" && !strings.HasPrefix(x.underlying, "chan<- ") {
+			return ""
+		}
+		return "len(" + x.text + ")$0"
+	}},
+	{label: "select", detail: "select { case v := <-%s: }", statement: true, expand: func(r *request, x postfixExpr) string {
+		if rangeKind(x.underlying) != "chan" {
+			return ""
+		}
+		return "select {\ncase ${1:v} := <-" + x.text + ":\n\t$0\n}"
+	}},
+}
+
+// postfixCandidates 类型适用的后缀补全，选中时把整个 x.name 替换为模板生成的代码
+func (r *request) postfixCandidates(base ast.Expr, typ string) []candidate {
+	if typ == "" || !r.isValue(base) {
+		return nil
+	}
+	text := r.gf.NodeText(base)
+	if text == "" {
+		return nil
+	}
+	// 模板总是按 snippet 生成，不支持 snippet 时再转换为普通文本，x 中的 \ 和 $ 都要转义
+	x := postfixExpr{text: escapeSnippet(text), typ: typ, underlying: r.underlying(typ), statement: r.gf.StartsStatement(base)}
+
+	// 多行的代码后面几行和 x 所在的行缩进相同
+	indent := r.gf.LineIndent(base.Pos())
+	start, end := r.gf.PositionOf(base.Pos()), r.gf.PositionOf(r.pos)
+	var candidates []candidate
+	for _, t := range postfixTemplates {
+		if t.statement && !x.statement {
+			continue
+		}
+		body := t.expand(r, x)
+		if body == "" {
+			continue
+		}
+		body = strings.ReplaceAll(body, "\n", "\n"+indent)
+		cand := candidate{
+			label:  t.label,
+			kind:   lsp.CIKSnippet,
+			detail: fmt.Sprintf(t.detail, text),
+			filter: text + "." + t.label,
+			scope:  lowScore,
+		}
+		if r.c.Config.SnippetSupport {
+			cand.snippet = body
+		} else {
+			body = snippetPlainText(body)
+		}
+		cand.replace = &file.TextEdit{Start: start, End: end, NewText: body}
+		if t.label == "print" {
+			if _, missing, _ := r.fmtImport(); missing {
+				if edit, ok := r.gf.ImportEdit("fmt"); ok {
+					cand.edit = &edit
+				}
+			}
+		}
+		candidates = append(candidates, cand)
+	}
+	return candidates
+}
+
+// postfixReturn 返回 x，所在函数的其他返回值使用零值，x 的类型不是任何一个返回值的类型时返回空字符串
+func (r *request) postfixReturn(x postfixExpr) string {
+	fn := r.gf.EnclosingFunc(r.pos)
+	if fn == nil {
+		return ""
+	}
+	index := -1
+	for i, ret := range fn.Returns {
+		if ret.Type == x.typ {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return ""
+	}
+	pkg := r.goPackage()
+	values := make([]string, 0, len(fn.Returns))
+	for i, ret := range fn.Returns {
+		if i == index {
+			values = append(values, x.text)
+			continue
+		}
+		zero := file.ZeroValue(ret.Type, pkg.LookupType)
+		values = append(values, fmt.Sprintf("${%d:%s}", len(values)+1, escapeSnippet(zero)))
+	}
+	return "return " + strings.Join(values, ", ") + "$0"
+}
+
+// fmtImport 调用 fmt 包中的函数使用的前缀，missing 表示还需要添加 import。
+// import . "fmt" 时直接调用，只有 import _ "fmt" 时不能调用，ok 为 false
+func (r *request) fmtImport() (prefix string, missing bool, ok bool) {
+	imports := r.gf.ImportsOf("fmt")
+	if len(imports) == 0 {
+		return "fmt.", true, true
+	}
+	// 同时有多个 fmt 的 import 时优先使用有名称的
+	dot := false
+	for _, spec := range imports {
+		switch {
+		case spec.IsDot():
+			dot = true
+		case !spec.IsBlank():
+			return spec.Name + ".", false, true
+		}
+	}
+	return "", false, dot
+}
+
+// isValue 表达式是不是值，类型名称和包名不是
+func (r *request) isValue(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return true
+	}
+	decl, _ := r.gf.LookupVisible(ident.Name, ident.Pos())
+	return decl == nil || decl.Type != file.DeclSpecTypeType && decl.Type != file.DeclSpecTypeImport
+}
+
+// nilable 类型的零值是否为 nil
+func (r *request) nilable(typ string) bool {
+	gopkg := r.goPackage()
+	lookup := func(name string) (*file.TypeSpec, bool) {
+		return r.lookupType(gopkg, name)
+	}
+	return file.ZeroValue(typ, lookup) == "nil"
+}
+
+// underlying 类型的底层类型，其他包中的类型需要查询索引或者解析标准库的源码
+func (r *request) underlying(typ string) string {
+	gopkg := r.goPackage()
+	for depth := 0; depth < maxUnderlyingDepth && isTypeName(typ); depth++ {
+		spec, ok := r.lookupType(gopkg, typ)
+		if !ok || spec.Type == "" || len(spec.TypeParams) > 0 {
+			break
+		}
+		typ = spec.Type
+	}
+	return typ
+}
+
+// rangeKind 可以 range 的类型的种类: slice(包括数组和数组指针)、map、string、chan，不能 range 时返回空字符串
+func rangeKind(underlying string) string {
+	switch {
+	case underlying == "string":
+		return "string"
+	case strings.HasPrefix(underlying, "[") || strings.HasPrefix(underlying, "*["):
+		return "slice"
+	case strings.HasPrefix(underlying, "map["):
+		return "map"
+	case strings.HasPrefix(underlying, "chan ") || strings.HasPrefix(underlying, "<-chan "):
+		return "chan"
+	}
+	return ""
+}
+
+// snippetPlainText 客户端不支持 snippet 时去掉 tab 位置，占位符和引用占位符的 $n 替换为默认的文本
+func snippetPlainText(s string) string {
+	defaults := make(map[string]string)
+	for _, m := range placeholderRegexp.FindAllStringSubmatch(s, -1) {
+		defaults[m[1]] = m[2]
+	}
+	var sb strings.Builder
+	inPlaceholder := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			sb.WriteByte(s[i])
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			// ${1:text} 保留 text
+			if colon := strings.IndexByte(s[i:], ':'); colon > 0 {
+				i += colon
+				inPlaceholder = true
+			}
+		case s[i] == '$':
+			j := i + 1
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			sb.WriteString(snippetUnescape(defaults[s[i+1:j]]))
+			i = j - 1
+		case s[i] == '}' && inPlaceholder:
+			inPlaceholder = false
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// placeholderRegexp 匹配没有嵌套的占位符 ${n:text}
+var placeholderRegexp = regexp.MustCompile(`\$\{(\d+):((?:[^}\\]|\\.)*)\}`)
+
+// snippetUnescape escapeSnippet 的逆操作
+func snippetUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\$`, `$`, `\}`, `}`).Replace(s)
+}
@@ -0,0 +1,74 @@
+package completion
+
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"testing"
+)
+
+func findPostfixTemplate(t *testing.T, label string) postfixTemplate {
+	t.Helper()
+	for _, tmpl := range postfixTemplates {
+		if tmpl.label == label {
+			return tmpl
+		}
+	}
+	t.Fatalf("postfix template %s not found", label)
+	return postfixTemplate{}
+}
+
+// 不支持 snippet 时，表达式中的 \、$ 和 } 原样保留
+func TestPostfixPlainText(t *testing.T) {
+	tests := []struct {
+		label      string
+		text       string
+		underlying string
+		want       string
+	}{
+		{"len", `strings.Split(s, "\n")`, "[]string", `len(strings.Split(s, "\n"))`},
+		{"range", `m["$k"]`, "map[string]int", "for k, v := range m[\"$k\"] {\n\t\n}"},
+		{"for", "`a\\b${1}`", "string", "for i := range `a\\b${1}` {\n\t\n}"},
+		{"select", "chs[`}`]", "chan int", "select {\ncase v := <-chs[`}`]:\n\t\n}"},
+		{"keys", `m["\\"]`, "map[string]int", "keys := make([]string, 0, len(m[\"\\\\\"]))\nfor k := range m[\"\\\\\"] {\n\tkeys = append(keys, k)\n}"},
+	}
+	for _, tt := range tests {
+		tmpl := findPostfixTemplate(t, tt.label)
+		body := tmpl.expand(nil, postfixExpr{text: escapeSnippet(tt.text), underlying: tt.underlying})
+		if got := snippetPlainText(body); got != tt.want {
+			t.Errorf("%s: plain text of %q = %q, want %q", tt.label, body, got, tt.want)
+		}
+	}
+}
+
+func TestPostfixPrint(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports string
+		want    string // 为空时不提供模板
+		missing bool
+	}{
+		{"not imported", "", `fmt.Println(s + "$")`, true},
+		{"imported", `import "fmt"`, `fmt.Println(s + "$")`, false},
+		{"alias", `import f "fmt"`, `f.Println(s + "$")`, false},
+		{"dot", `import . "fmt"`, `Println(s + "$")`, false},
+		{"blank", `import _ "fmt"`, "", false},
+		{"blank and named", "import (\n\t_ \"fmt\"\n\tf \"fmt\"\n)", `f.Println(s + "$")`, false},
+	}
+	tmpl := findPostfixTemplate(t, "print")
+	for _, tt := range tests {
+		gf, err := file.ParseGoCode("/work/main.go", []byte("package main\n\n"+tt.imports+"\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &request{gf: gf}
+		var got string
+		if body := tmpl.expand(r, postfixExpr{text: escapeSnippet(`s + "$"`)}); body != "" {
+			got = snippetPlainText(body)
+		}
+		if got != tt.want {
+			t.Errorf("%s: print = %q, want %q", tt.name, got, tt.want)
+		}
+		if _, missing, _ := r.fmtImport(); missing != tt.missing {
+			t.Errorf("%s: missing fmt import = %v, want %v", tt.name, missing, tt.missing)
+		}
+	}
+}
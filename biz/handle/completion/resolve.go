@@ -0,0 +1,109 @@
+package completion
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/token"
+	"pkg.nimblebun.works/go-lsp"
+	"sync"
+)
+
+// resolveData 补全项的 Data，ID 是产生这个补全项的请求，不是最近一次请求时在同一个位置重新计算候选项
+type resolveData struct {
+	ID       int             `json:"id"`
+	URI      lsp.DocumentURI `json:"uri"`
+	Position lsp.Position    `json:"position"`
+}
+
+// lastCompletion 最近一次补全请求返回的候选项的 resolve，客户端通常只 resolve 最近一次返回的列表中的项
+var lastCompletion struct {
+	sync.Mutex
+	id       int
+	resolves map[string]func() (detail string, doc string) // 名称 -> candidate.resolve
+}
+
+// saveResolves 保存这次补全返回的候选项的 resolve，返回这次请求的 ID
+func saveResolves(ranked []scored) int {
+	resolves := make(map[string]func() (string, string))
+	for _, cand := range ranked {
+		if _, ok := resolves[cand.label]; !ok && cand.resolve != nil {
+			resolves[cand.label] = cand.resolve
+		}
+	}
+	lastCompletion.Lock()
+	defer lastCompletion.Unlock()
+	lastCompletion.id++
+	lastCompletion.resolves = resolves
+	return lastCompletion.id
+}
+
+// findResolve 查找名为 label 的补全项的 resolve，先从最近一次请求中查找
+func findResolve(c *engine.LspService, data resolveData, label string) func() (string, string) {
+	lastCompletion.Lock()
+	if data.ID != 0 && data.ID == lastCompletion.id {
+		resolve := lastCompletion.resolves[label]
+		lastCompletion.Unlock()
+		return resolve
+	}
+	lastCompletion.Unlock()
+
+	req, err := newRequest(c, &lsp.CompletionParams{
+		TextDocumentPositionParams: lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: data.URI},
+			Position:     data.Position,
+		},
+	})
+	if err != nil {
+		return nil
+	}
+	for _, cand := range req.candidates() {
+		if cand.label == label {
+			return cand.resolve
+		}
+	}
+	return nil
+}
+
+// Resolve 补全列表只包含名称和简短的描述，选中某一项时再计算完整的签名、文档注释和是否已经废弃
+func Resolve(ctx context.Context, c *engine.LspService, item *lsp.CompletionItem) (*lsp.CompletionItem, error) {
+	var data resolveData
+	raw, err := json.Marshal(item.Data)
+	if err != nil || json.Unmarshal(raw, &data) != nil || data.URI == "" {
+		return item, nil
+	}
+	resolve := findResolve(c, data, item.Label)
+	if resolve == nil {
+		return item, nil
+	}
+	detail, doc := resolve()
+	if detail != "" {
+		item.Detail = detail
+	}
+	if doc != "" {
+		item.Documentation = lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: file.DocMarkdown(doc)}
+	}
+	if file.IsDeprecated(doc) {
+		item.Tags = append(item.Tags, lsp.CITDeprecated)
+	}
+	return item, nil
+}
+
+// sourceDoc 读取 filename 中 line:column 处声明的文档注释，行列从 1 开始
+func sourceDoc(filename string, line, column int) string {
+	code, err := document.Read(file.PathToURI(filename))
+	if err != nil {
+		return ""
+	}
+	gf, err := file.ParseGoCode(filename, code)
+	if err != nil {
+		return ""
+	}
+	tf := gf.FileSet.File(gf.File.Pos())
+	if tf == nil || line < 1 || line > tf.LineCount() || column < 1 {
+		return ""
+	}
+	return gf.DocAt(tf.LineStart(line) + token.Pos(column-1))
+}
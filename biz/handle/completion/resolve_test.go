@@ -0,0 +1,114 @@
+package completion
+
+import (
+	"context"
+	"encoding/json"
+	"pkg.nimblebun.works/go-lsp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const resolveCode = `package m
+
+import "strings"
+
+// helper 返回 a 的字符串
+func helper(a int) string { return "" }
+
+// Deprecated: 使用 helper
+func old() {}
+
+type T struct {
+	// Name 名称
+	Name string ` + "`json:\"name\"`" + `
+}
+
+func f(t T) {
+	%s
+}
+`
+
+// roundTrip 和客户端一样把补全项序列化后再发回来
+func roundTrip(t *testing.T, item lsp.CompletionItem) *lsp.CompletionItem {
+	t.Helper()
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out lsp.CompletionItem
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return &out
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string // f 中光标所在的行
+		label      string
+		detail     string
+		doc        string
+		deprecated bool
+	}{
+		{"func", "hel" + cursorMark, "helper", "func helper(a int) string", "helper 返回 a 的字符串", false},
+		{"deprecated", "ol" + cursorMark, "old", "func old()", "Deprecated: 使用 helper", true},
+		{"field", "t.Na" + cursorMark, "Name", "field Name string `json:\"name\"`", "Name 名称", false},
+		{"std member", "strings.ToUp" + cursorMark, "ToUpper", "func ToUpper(s string) string", "ToUpper returns", false},
+	}
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		list := complete(t, c, dir, "m.go", strings.Replace(resolveCode, "%s", tt.line, 1))
+		item, ok := findItem(list, tt.label)
+		if !ok {
+			t.Errorf("%s: %s not found", tt.name, tt.label)
+			continue
+		}
+		// 列表中不计算描述，选中时才计算
+		if item.Detail != "" || item.Data == nil {
+			t.Errorf("%s: unresolved item detail = %q, data = %v", tt.name, item.Detail, item.Data)
+		}
+		resolved, err := Resolve(context.Background(), c, roundTrip(t, item))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(resolved.Detail, tt.detail) {
+			t.Errorf("%s: detail = %q, want %q", tt.name, resolved.Detail, tt.detail)
+		}
+		doc, _ := resolved.Documentation.(lsp.MarkupContent)
+		if !strings.Contains(doc.Value, tt.doc) {
+			t.Errorf("%s: doc = %q, want %q", tt.name, doc.Value, tt.doc)
+		}
+		if got := slices.Contains(resolved.Tags, lsp.CITDeprecated); got != tt.deprecated {
+			t.Errorf("%s: deprecated = %v, want %v", tt.name, got, tt.deprecated)
+		}
+	}
+}
+
+// 不是最近一次请求返回的补全项，在补全项记录的位置重新计算候选项
+func TestResolveStaleItem(t *testing.T) {
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, false)
+	code := strings.Replace(resolveCode, "%s", "hel"+cursorMark, 1)
+	item, ok := findItem(complete(t, c, dir, "m.go", code), "helper")
+	if !ok {
+		t.Fatal("helper not found")
+	}
+	// 另一个文件中的请求替换了最近一次请求的候选项
+	complete(t, c, dir, "other.go", "package m\n\nfunc g() {\n\tx"+cursorMark+"\n}\n")
+	resolved, err := Resolve(context.Background(), c, roundTrip(t, item))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Detail != "func helper(a int) string" {
+		t.Errorf("stale item detail = %q", resolved.Detail)
+	}
+
+	// 没有 Data 的补全项原样返回
+	plain := lsp.CompletionItem{Label: "for"}
+	if got, err := Resolve(context.Background(), c, &plain); err != nil || got.Detail != "" || got.Documentation != nil {
+		t.Errorf("item without data = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,187 @@
+package completion
+
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"github.com/denstiny/golang-language-server/pkg/fuzzy"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 候选项的相关度，最终的分数是匹配程度、作用域、类型和最近使用情况的乘积
+const (
+	lowScore  float64 = 0.01
+	stdScore  float64 = 1.0
+	highScore float64 = 100.0
+)
+
+// 作用域由近到远: 局部声明 highScore，包级别声明 packageScore，导入的包、成员、内置符号和关键字 stdScore，未导入的包 lowScore
+const packageScore = 10 * stdScore
+
+// typeMatchScore 类型和光标处需要的类型相同
+const typeMatchScore = 10 * stdScore
+
+// maxCompletionItems 一次最多返回的候选项，超过时返回分数最高的部分，继续输入时客户端重新请求
+const maxCompletionItems = 200
+
+// scored 和 prefix 匹配的候选项
+type scored struct {
+	candidate
+	score float64
+}
+
+// rank 过滤掉和 prefix 不匹配的候选项，按分数从高到低排序，同名的候选项只保留第一个
+func (r *request) rank(candidates []candidate) []scored {
+	var result []scored
+	seen := make(map[string]bool)
+	for _, cand := range candidates {
+		if seen[cand.label] {
+			continue
+		}
+		seen[cand.label] = true
+		match, ok := fuzzy.Score(r.prefix, cand.label)
+		if !ok {
+			continue
+		}
+		score := match * recentScore(cand.label)
+		if cand.scope != 0 {
+			score *= cand.scope
+		}
+		result = append(result, scored{candidate: cand, score: score})
+	}
+	r.scoreTypes(result)
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].score != result[j].score {
+			return result[i].score > result[j].score
+		}
+		return result[i].label < result[j].label
+	})
+	return result
+}
+
+// scoreTypes 类型和光标处需要的类型相同的候选项分数更高，计算类型的代价比较大，
+// 只计算通过过滤、并且加分后能排进返回结果的候选项
+func (r *request) scoreTypes(result []scored) {
+	if len(result) == 0 {
+		return
+	}
+	expected := r.gf.ExpectedType(r.pos, r.resolver)
+	if expected == "" {
+		return
+	}
+	// 第 maxCompletionItems 高的分数，加分后仍然低于它的候选项一定会被截断
+	cutoff := 0.0
+	if len(result) > maxCompletionItems {
+		scores := make([]float64, len(result))
+		for i, cand := range result {
+			scores[i] = cand.score
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+		cutoff = scores[maxCompletionItems-1]
+	}
+	for i := range result {
+		cand := &result[i]
+		if cand.typ != nil && cand.score*typeMatchScore >= cutoff && typeMatches(expected, valueType(cand.candidate)) {
+			cand.score *= typeMatchScore
+		}
+	}
+}
+
+// valueType 候选项作为表达式的类型，只有一个返回值的函数使用返回值的类型
+func valueType(cand candidate) string {
+	typ := cand.typ()
+	if !strings.HasPrefix(typ, "func") {
+		return typ
+	}
+	fn, err := file.ParseFuncSignature("", typ)
+	if err != nil || len(fn.Returns) != 1 {
+		return typ
+	}
+	return fn.Returns[0].Type
+}
+
+// typeMatches 类型是否相同，any 可以接收任何值，没有区分度
+func typeMatches(expected, typ string) bool {
+	if expected == "any" || expected == "interface{}" {
+		return false
+	}
+	return typ != "" && strings.ReplaceAll(expected, " ", "") == strings.ReplaceAll(typ, " ", "")
+}
+
+// maxRecentItems 最多记录的最近使用的名称，超过时丢掉最久没有使用的
+const maxRecentItems = 100
+
+// recentItems 本次会话中使用过的补全项
+var recentItems struct {
+	sync.Mutex
+	clock int
+	used  map[string]int // 名称 -> 最近一次使用时的 clock
+}
+
+// recordUse 记录使用了名为 label 的补全项
+func recordUse(label string) {
+	recentItems.Lock()
+	defer recentItems.Unlock()
+	if recentItems.used == nil {
+		recentItems.used = make(map[string]int)
+	}
+	recentItems.clock++
+	recentItems.used[label] = recentItems.clock
+	if len(recentItems.used) <= maxRecentItems {
+		return
+	}
+	oldest := ""
+	for name, used := range recentItems.used {
+		if oldest == "" || used < recentItems.used[oldest] {
+			oldest = name
+		}
+	}
+	delete(recentItems.used, oldest)
+}
+
+// recentScore 最近使用过的名称分数更高，最近一次使用的加倍，之后使用的名称越多加成越少
+func recentScore(label string) float64 {
+	recentItems.Lock()
+	defer recentItems.Unlock()
+	used, ok := recentItems.used[label]
+	if !ok {
+		return stdScore
+	}
+	return stdScore + stdScore/float64(1+recentItems.clock-used)
+}
+
+// lastWord 上一次补全请求正在输入的标识符的位置和返回的名称
+var lastWord struct {
+	sync.Mutex
+	uri    string
+	word   file.Position
+	labels map[string]bool
+}
+
+// saveWord 保存这次请求的位置和返回的名称，import 路径不是标识符，不记录
+func (r *request) saveWord(ranked []scored) {
+	lastWord.Lock()
+	defer lastWord.Unlock()
+	lastWord.uri, lastWord.word, lastWord.labels = r.uri, r.word, nil
+	if r.importStart != nil {
+		return
+	}
+	lastWord.labels = make(map[string]bool, len(ranked))
+	for _, cand := range ranked {
+		lastWord.labels[cand.label] = true
+	}
+}
+
+// recordTyped 不依赖客户端通知选中了哪一项: 光标离开上一次请求的标识符后，
+// 那个位置上的标识符是上一次返回的某个名称时，说明这一项被选中或者完整地输入了
+func (r *request) recordTyped() {
+	lastWord.Lock()
+	defer lastWord.Unlock()
+	if lastWord.labels == nil || lastWord.uri != r.uri || lastWord.word == r.word {
+		return
+	}
+	if word := r.gf.IdentAfter(lastWord.word); lastWord.labels[word] {
+		recordUse(word)
+	}
+	lastWord.labels = nil
+}
@@ -0,0 +1,166 @@
+package completion
+
+import (
+	"fmt"
+	"testing"
+)
+
+// resetRecent 清空最近使用的记录和上一次请求，避免其他测试的请求影响排序
+func resetRecent() {
+	recentItems.Lock()
+	recentItems.clock, recentItems.used = 0, nil
+	recentItems.Unlock()
+	lastWord.Lock()
+	lastWord.uri, lastWord.labels = "", nil
+	lastWord.Unlock()
+}
+
+func recentlyUsed(label string) bool {
+	recentItems.Lock()
+	defer recentItems.Unlock()
+	_, ok := recentItems.used[label]
+	return ok
+}
+
+// indexOf 名为 label 的补全项在结果中的位置，没有时返回 -1
+func indexOf(items []string, label string) int {
+	for i, item := range items {
+		if item == label {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestCompletionRanking(t *testing.T) {
+	// 同一个包的另一个文件
+	other := "package m\n\nvar total int\n\nvar title string\n"
+	tests := []struct {
+		name   string
+		code   string
+		recent []string
+		order  []string // 这些补全项在结果中的先后顺序
+	}{
+		{
+			name:  "local before package",
+			code:  "package m\n\nfunc f() {\n\ttally := 1\n\t_ = t" + cursorMark + "\n}\n",
+			order: []string{"tally", "title", "total"},
+		},
+		{
+			name:  "type match",
+			code:  "package m\n\nfunc f() {\n\tvar s string = t" + cursorMark + "\n\t_ = s\n}\n",
+			order: []string{"title", "total"},
+		},
+		{
+			name:   "recently used",
+			code:   "package m\n\nfunc f() {\n\t_ = t" + cursorMark + "\n}\n",
+			recent: []string{"total"},
+			order:  []string{"total", "title"},
+		},
+		{
+			name:   "local before recently used package",
+			code:   "package m\n\nfunc f() {\n\ttally := 1\n\t_ = t" + cursorMark + "\n}\n",
+			recent: []string{"total"},
+			order:  []string{"tally", "total", "title"},
+		},
+	}
+	dir := writeModule(t, map[string]string{"other.go": other})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		resetRecent()
+		for _, label := range tt.recent {
+			recordUse(label)
+		}
+		list := complete(t, c, dir, "m.go", tt.code)
+		var got []string
+		for _, item := range list.Items {
+			got = append(got, item.Label)
+		}
+		last := -1
+		for _, label := range tt.order {
+			i := indexOf(got, label)
+			if i < 0 {
+				t.Errorf("%s: missing %s", tt.name, label)
+				break
+			}
+			if i < last {
+				t.Errorf("%s: %s ranked before %s in %v", tt.name, label, tt.order, got)
+				break
+			}
+			last = i
+		}
+	}
+	resetRecent()
+}
+
+// 客户端不通知选中了哪一项，光标离开上一次请求的标识符时按那个位置上的标识符记录
+func TestRecordTyped(t *testing.T) {
+	other := "package m\n\nvar title string\n"
+	tests := []struct {
+		name   string
+		first  string // 第一次请求
+		second string // 继续编辑后的请求
+		label  string
+		want   bool // 是否记录了 label
+	}{
+		{
+			name:   "accepted",
+			first:  "package m\n\nfunc f() {\n\t_ = ti" + cursorMark + "\n}\n",
+			second: "package m\n\nfunc f() {\n\t_ = title\n\t" + cursorMark + "\n}\n",
+			label:  "title",
+			want:   true,
+		},
+		{
+			name:   "still typing",
+			first:  "package m\n\nfunc f() {\n\t_ = ti" + cursorMark + "\n}\n",
+			second: "package m\n\nfunc f() {\n\t_ = titl" + cursorMark + "\n}\n",
+			label:  "title",
+		},
+		{
+			name:   "other name",
+			first:  "package m\n\nfunc f() {\n\t_ = ti" + cursorMark + "\n}\n",
+			second: "package m\n\nfunc f() {\n\t_ = tim\n\t" + cursorMark + "\n}\n",
+			label:  "title",
+		},
+		{
+			name:   "import path",
+			first:  "package m\n\nimport \"ti" + cursorMark + "\"\n",
+			second: "package m\n\nimport \"time\"\n\n" + cursorMark + "\n",
+			label:  "time",
+		},
+	}
+	dir := writeModule(t, map[string]string{"other.go": other})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		resetRecent()
+		complete(t, c, dir, "m.go", tt.first)
+		complete(t, c, dir, "m.go", tt.second)
+		if got := recentlyUsed(tt.label); got != tt.want {
+			t.Errorf("%s: recorded %s = %v, want %v", tt.name, tt.label, got, tt.want)
+		}
+	}
+	resetRecent()
+}
+
+func TestRecordUseLimit(t *testing.T) {
+	resetRecent()
+	defer resetRecent()
+	for i := 0; i <= maxRecentItems; i++ {
+		recordUse(fmt.Sprint("name", i))
+	}
+	// 再次使用的名称变成最近使用的
+	recordUse("name1")
+	recordUse("extra")
+	if recentlyUsed("name0") || recentlyUsed("name2") {
+		t.Error("oldest names are not dropped")
+	}
+	if !recentlyUsed("name1") || !recentlyUsed("extra") {
+		t.Error("recent names are dropped")
+	}
+	recentItems.Lock()
+	n := len(recentItems.used)
+	recentItems.Unlock()
+	if n != maxRecentItems {
+		t.Errorf("recorded %d names, want %d", n, maxRecentItems)
+	}
+}
@@ -0,0 +1,191 @@
+package completion
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userSnippetFile 配置目录中用户自定义 snippet 的文件名
+const userSnippetFile = "snippets.json"
+
+// snippet 代码片段，Body 使用 lsp 的 snippet 语法，比如 $1 ${2:name} $0
+type snippet struct {
+	Prefix      string      `json:"prefix"`
+	Body        snippetBody `json:"body"`
+	Description string      `json:"description"`
+	Context     string      `json:"context"` // toplevel、statement、expression、type，为空时等同于 statement
+	Test        bool        `json:"test"`    // 只在 _test.go 文件中使用
+	Import      string      `json:"import"`  // Body 中使用的包，选中时还没有导入则添加 import
+	// expand 根据光标所在的函数生成 Body，返回空字符串时不提供这个 snippet
+	expand func(r *request) string
+}
+
+// snippetBody 和 VS Code 的 snippet 文件一样，body 可以是字符串或者按行分开的字符串数组
+type snippetBody string
+
+func (b *snippetBody) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*b = snippetBody(strings.Join(lines, "\n"))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*b = snippetBody(s)
+	return nil
+}
+
+var snippetContexts = map[string]file.CursorContextKind{
+	"":           file.ContextStatement,
+	"toplevel":   file.ContextTopLevel,
+	"statement":  file.ContextStatement,
+	"expression": file.ContextExpression,
+	"type":       file.ContextType,
+}
+
+var builtinSnippets = []snippet{
+	{Prefix: FOR, Body: "for ${1:i} := range ${2:n} {\n\t$0\n}", Description: "for range"},
+	{Prefix: "fori", Body: "for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}", Description: "for i := 0; i < n; i++"},
+	{Prefix: IF, Body: "if ${1:cond} {\n\t$0\n}", Description: "if statement"},
+	{Prefix: "iferr", Description: "if err != nil { return ..., err }", expand: (*request).iferrSnippet},
+	{Prefix: SWITCH, Body: "switch ${1:x} {\ncase ${2:v}:\n\t$0\n}", Description: "switch statement"},
+	{Prefix: SELECT, Body: "select {\ncase ${1:v} := <-${2:ch}:\n\t$0\n}", Description: "select statement"},
+	{Prefix: "gofunc", Body: "go func() {\n\t$0\n}()", Description: "go func() {}()"},
+	{Prefix: FUNC, Body: "func ${1:name}($2) $3 {\n\t$0\n}", Description: "function declaration", Context: "toplevel"},
+	{Prefix: FUNC, Body: "func($1) $2 {\n\t$0\n}", Description: "function literal", Context: "expression"},
+	{
+		Prefix:      "test",
+		Body:        "func Test${1:Name}(t *testing.T) {\n\t$0\n}",
+		Description: "test function",
+		Context:     "toplevel",
+		Test:        true,
+		Import:      "testing",
+	},
+	{
+		Prefix:      "bench",
+		Body:        "func Benchmark${1:Name}(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t\t$0\n\t}\n}",
+		Description: "benchmark function",
+		Context:     "toplevel",
+		Test:        true,
+		Import:      "testing",
+	},
+}
+
+// userSnippets 用户 snippet 的缓存，文件修改后重新读取
+var userSnippets struct {
+	sync.Mutex
+	filename string
+	modTime  time.Time
+	snippets []snippet
+}
+
+// loadUserSnippets 读取配置目录中的 snippets.json，格式和 VS Code 的 snippet 文件相同:
+// {"name": {"prefix": "...", "body": ["..."], "description": "...", "context": "statement", "import": "fmt"}}
+func loadUserSnippets(dir string) []snippet {
+	filename := filepath.Join(dir, userSnippetFile)
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil
+	}
+
+	userSnippets.Lock()
+	defer userSnippets.Unlock()
+	if filename == userSnippets.filename && info.ModTime().Equal(userSnippets.modTime) {
+		return userSnippets.snippets
+	}
+	userSnippets.filename = filename
+	userSnippets.modTime = info.ModTime()
+	userSnippets.snippets = nil
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.Error().Msg("read user snippets failed: " + err.Error())
+		return nil
+	}
+	var named map[string]snippet
+	if err = json.Unmarshal(data, &named); err != nil {
+		log.Error().Msg("parse user snippets failed: " + err.Error())
+		return nil
+	}
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := named[name]
+		if s.Prefix == "" {
+			s.Prefix = name
+		}
+		if _, ok := snippetContexts[s.Context]; !ok {
+			log.Error().Msg(fmt.Sprintf("user snippet %s: unknown context %q", name, s.Context))
+			continue
+		}
+		userSnippets.snippets = append(userSnippets.snippets, s)
+	}
+	return userSnippets.snippets
+}
+
+// snippetCandidates 光标处可以使用的 snippet，用户的 snippet 在前面，可以覆盖同名的内置 snippet
+func (r *request) snippetCandidates() []candidate {
+	if !r.c.Config.SnippetSupport {
+		return nil
+	}
+	isTest := strings.HasSuffix(r.filename, "_test.go")
+	var candidates []candidate
+	for _, s := range append(loadUserSnippets(r.c.Config.ServerConfigDir), builtinSnippets...) {
+		if s.Test && !isTest || snippetContexts[s.Context] != r.context.Kind {
+			continue
+		}
+		body := string(s.Body)
+		if s.expand != nil {
+			body = s.expand(r)
+		}
+		if body == "" {
+			continue
+		}
+		cand := candidate{label: s.Prefix, kind: lsp.CIKSnippet, detail: s.Description, snippet: body}
+		if s.Import != "" {
+			if edit, ok := r.gf.ImportEdit(s.Import); ok {
+				cand.edit = &edit
+			}
+		}
+		candidates = append(candidates, cand)
+	}
+	return candidates
+}
+
+// iferrSnippet 返回错误时其他返回值使用零值，所在函数的最后一个返回值不是 error 时不返回
+func (r *request) iferrSnippet() string {
+	fn := r.gf.EnclosingFunc(r.pos)
+	if fn == nil {
+		return ""
+	}
+	if len(fn.Returns) == 0 || fn.Returns[len(fn.Returns)-1].Type != "error" {
+		return "if err != nil {\n\t$0\n}"
+	}
+	pkg := r.goPackage()
+	values := make([]string, 0, len(fn.Returns))
+	for i, ret := range fn.Returns[:len(fn.Returns)-1] {
+		zero := file.ZeroValue(ret.Type, pkg.LookupType)
+		values = append(values, fmt.Sprintf("${%d:%s}", i+1, escapeSnippet(zero)))
+	}
+	values = append(values, "err")
+	return "if err != nil {\n\treturn " + strings.Join(values, ", ") + "\n}"
+}
+
+// escapeSnippet 转义 snippet 中有特殊含义的字符
+func escapeSnippet(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"testing"
+	"time"
+)
+
+func TestSnippetCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		code     string
+		label    string
+		want     bool
+		imports  string // 选中时添加的 import，为空时没有额外的修改
+	}{
+		{"statement", "m.go", "package m\n\nfunc f() {\n\tfo" + cursorMark + "\n}\n", "fori", true, ""},
+		{"not at top level", "m.go", "package m\n\nfo" + cursorMark + "\n", "fori", false, ""},
+		{"func declaration", "m.go", "package m\n\nfu" + cursorMark + "\n", "func", true, ""},
+		{"test outside test file", "m.go", "package m\n\nte" + cursorMark + "\n", "test", false, ""},
+		{"test adds import", "m_test.go", "package m\n\nte" + cursorMark + "\n", "test", true, "\n\nimport \"testing\""},
+		{"bench adds import", "m_test.go", "package m\n\nimport \"fmt\"\n\nbe" + cursorMark + "\n", "bench", true, "import (\n\t\"fmt\"\n\t\"testing\"\n)"},
+		{"test already imported", "m_test.go", "package m\n\nimport \"testing\"\n\nte" + cursorMark + "\n", "test", true, ""},
+	}
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, true)
+	for _, tt := range tests {
+		list := complete(t, c, dir, tt.filename, tt.code)
+		var item lsp.CompletionItem
+		found := false
+		for _, it := range list.Items {
+			if it.Label == tt.label && it.Kind == lsp.CIKSnippet {
+				item, found = it, true
+			}
+		}
+		if found != tt.want {
+			t.Errorf("%s: snippet %s found = %v, want %v", tt.name, tt.label, found, tt.want)
+			continue
+		}
+		var imports string
+		if len(item.AdditionalTextEdits) > 0 {
+			imports = item.AdditionalTextEdits[0].NewText
+		}
+		if imports != tt.imports {
+			t.Errorf("%s: import edit = %q, want %q", tt.name, imports, tt.imports)
+		}
+	}
+	// 不支持 snippet 的客户端没有 snippet
+	list := complete(t, testService(dir, false), dir, "m.go", "package m\n\nfunc f() {\n\tfo"+cursorMark+"\n}\n")
+	for _, item := range list.Items {
+		if item.Kind == lsp.CIKSnippet {
+			t.Errorf("snippet %s offered to a client without snippet support", item.Label)
+		}
+	}
+}
+
+func TestIferrSnippet(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string // 为空时不提供 iferr
+	}{
+		{"values", "func f() (int, string, error) {\n\tiferr" + cursorMark + "\n}", "if err != nil {\n\treturn ${1:0}, ${2:\"\"}, err\n}"},
+		{"only error", "func f() error {\n\tiferr" + cursorMark + "\n}", "if err != nil {\n\treturn err\n}"},
+		{"struct and pointer", "type T struct{}\n\nfunc f() (T, *T, error) {\n\tiferr" + cursorMark + "\n}", "if err != nil {\n\treturn ${1:T{\\}}, ${2:nil}, err\n}"},
+		{"no error", "func f() int {\n\tiferr" + cursorMark + "\n}", "if err != nil {\n\t$0\n}"},
+		{"no results", "func f() {\n\tiferr" + cursorMark + "\n}", "if err != nil {\n\t$0\n}"},
+		{"keyword prefix", "func f() error {\n\tif" + cursorMark + "\n}", "if err != nil {\n\treturn err\n}"},
+		{"func literal", "func f() error {\n\tg := func() (bool, error) {\n\t\tiferr" + cursorMark + "\n\t}\n}", "if err != nil {\n\treturn ${1:false}, err\n}"},
+	}
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, true)
+	for _, tt := range tests {
+		item, ok := findItem(complete(t, c, dir, "m.go", "package m\n\n"+tt.code+"\n"), "iferr")
+		if !ok {
+			t.Errorf("%s: iferr not found", tt.name)
+			continue
+		}
+		if item.InsertText != tt.want {
+			t.Errorf("%s: iferr = %q, want %q", tt.name, item.InsertText, tt.want)
+		}
+	}
+}
+
+func TestUserSnippets(t *testing.T) {
+	configDir := t.TempDir()
+	filename := filepath.Join(configDir, userSnippetFile)
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{
+	"log": {"body": ["log.Println($1)", "$0"], "description": "log line", "import": "log"},
+	"fori": {"prefix": "fori", "body": "for $1 {}", "description": "user fori"},
+	"bad": {"prefix": "bad", "body": "x", "context": "nowhere"},
+	"ty": {"prefix": "mymap", "body": "map[string]$1", "context": "type"}
+}`, time.Now().Add(-time.Hour))
+
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, true)
+	c.Config.ServerConfigDir = configDir
+	code := "package m\n\nfunc f() {\n\t" + cursorMark + "\n}\n"
+	list := complete(t, c, dir, "m.go", code)
+
+	item, ok := findItem(list, "log")
+	if !ok || item.InsertText != "log.Println($1)\n$0" || item.Detail != "log line" {
+		t.Errorf("log snippet = %+v, %v", item, ok)
+	} else if len(item.AdditionalTextEdits) != 1 || item.AdditionalTextEdits[0].NewText != "\n\nimport \"log\"" {
+		t.Errorf("log snippet import edit = %+v", item.AdditionalTextEdits)
+	}
+	// 用户的 snippet 覆盖同名的内置 snippet
+	if item, ok := findItem(list, "fori"); !ok || item.Detail != "user fori" {
+		t.Errorf("fori = %+v, %v, want the user snippet", item, ok)
+	}
+	for _, label := range []string{"bad", "mymap"} {
+		if _, ok := findItem(list, label); ok {
+			t.Errorf("%s should not be offered in a statement", label)
+		}
+	}
+
+	// 文件修改后重新读取
+	write(`{"hello": {"body": "hello()"}}`, time.Now())
+	list = complete(t, c, dir, "m.go", code)
+	if _, ok := findItem(list, "hello"); !ok {
+		t.Errorf("snippet added after the file changed is missing")
+	}
+	if _, ok := findItem(list, "log"); ok {
+		t.Errorf("removed snippet log is still offered")
+	}
+}
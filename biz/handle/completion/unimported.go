@@ -0,0 +1,301 @@
+package completion
+
+import (
+	"fmt"
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"github.com/denstiny/golang-language-server/pkg/semantic"
+	"go/token"
+	"golang.org/x/mod/modfile"
+	"os"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// moduleInfo 当前文件所在 module 的 go.mod，用于给同名的包排序
+type moduleInfo struct {
+	path     string            // module 路径
+	dir      string            // go.mod 所在目录
+	requires map[string]bool   // 依赖的 module 路径 -> 是否为直接依赖
+	versions map[string]string // 依赖的 module 路径 -> 版本
+}
+
+// loadModule 从文件所在目录向上查找 go.mod，找不到时返回空的 moduleInfo
+func loadModule(filename string) *moduleInfo {
+	for dir := filepath.Dir(filename); ; dir = filepath.Dir(dir) {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			mod, err := modfile.ParseLax(filepath.Join(dir, "go.mod"), data, nil)
+			if err != nil || mod.Module == nil {
+				break
+			}
+			info := &moduleInfo{path: mod.Module.Mod.Path, dir: dir, requires: make(map[string]bool), versions: make(map[string]string)}
+			for _, req := range mod.Require {
+				info.requires[req.Mod.Path] = !req.Indirect
+				info.versions[req.Mod.Path] = req.Mod.Version
+			}
+			return info
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	return &moduleInfo{}
+}
+
+// packagePath 文件所在包的 import 路径，不在 module 中时返回空
+func (m *moduleInfo) packagePath(filename string) string {
+	if m.path == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(m.dir, filepath.Dir(filename))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	if rel == "." {
+		return m.path
+	}
+	return m.path + "/" + filepath.ToSlash(rel)
+}
+
+// require 返回 path 所属的依赖 module 是否为直接依赖，不是依赖时 ok 为 false
+func (m *moduleInfo) require(path string) (direct bool, ok bool) {
+	best := ""
+	for mod := range m.requires {
+		if (path == mod || strings.HasPrefix(path, mod+"/")) && len(mod) > len(best) {
+			best = mod
+		}
+	}
+	if best == "" {
+		return false, false
+	}
+	return m.requires[best], true
+}
+
+func (r *request) module() *moduleInfo {
+	if r.mod == nil {
+		r.mod = loadModule(r.filename)
+	}
+	return r.mod
+}
+
+// importablePackage 可以导入的包
+type importablePackage struct {
+	name string
+	path string
+}
+
+// unimportedPackages 名称以 prefix 开头、还没有导入也没有被本地声明遮蔽的包，同名的包只保留排序最靠前的一个
+func (r *request) unimportedPackages(prefix string) []importablePackage {
+	found := make(map[string][]string) // 包名 -> import 路径
+	for name, paths := range file.StdPackages() {
+		if matchPrefix(name, prefix) {
+			found[name] = append(found[name], paths...)
+		}
+	}
+	if pkgs, err := cache.SearchPackages(prefix, r.resolver.Workspace); err == nil {
+		for _, pkg := range pkgs {
+			if matchPrefix(pkg.PackageName, prefix) {
+				found[pkg.PackageName] = append(found[pkg.PackageName], pkg.Name)
+			}
+		}
+	}
+
+	self := r.module().packagePath(r.filename)
+	var result []importablePackage
+	for name, paths := range found {
+		if _, ok := r.gf.ImportByName(name); ok || r.declared(name) {
+			continue
+		}
+		var candidates []string
+		for _, path := range paths {
+			if path != self && importable(path, self) {
+				candidates = append(candidates, path)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			ri, rj := r.importRank(candidates[i]), r.importRank(candidates[j])
+			if ri != rj {
+				return ri > rj
+			}
+			if len(candidates[i]) != len(candidates[j]) {
+				return len(candidates[i]) < len(candidates[j])
+			}
+			return candidates[i] < candidates[j]
+		})
+		result = append(result, importablePackage{name: name, path: candidates[0]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// importRank 同名包的优先级: 同一个包的其他文件已经导入 > 当前 module 中的包 > 直接依赖和标准库 > 间接依赖 > 其他
+func (r *request) importRank(path string) int {
+	for _, gf := range r.packageFiles() {
+		if len(gf.ImportsOf(path)) > 0 {
+			return 4
+		}
+	}
+	mod := r.module()
+	if mod.path != "" && (path == mod.path || strings.HasPrefix(path, mod.path+"/")) {
+		return 3
+	}
+	if direct, ok := mod.require(path); ok {
+		if direct {
+			return 2
+		}
+		return 1
+	}
+	if file.IsStdImport(path) {
+		return 2
+	}
+	return 0
+}
+
+// declared name 是否在光标处可见，或者是同一个包中其他文件的包级别声明
+func (r *request) declared(name string) bool {
+	if decl, _ := r.gf.LookupVisible(name, r.pos); decl != nil {
+		return true
+	}
+	for _, gf := range r.packageFiles() {
+		for _, decl := range gf.Scope.Decls {
+			if decl.Name == name && decl.Type != file.DeclSpecTypeImport {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importable internal 包只能被 internal 的父目录下的包导入
+func importable(path, from string) bool {
+	elems := strings.Split(path, "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if elems[i] != "internal" {
+			continue
+		}
+		if i == 0 {
+			return false
+		}
+		parent := strings.Join(elems[:i], "/")
+		return from == parent || strings.HasPrefix(from, parent+"/")
+	}
+	return true
+}
+
+// unimportedCandidates 名称以 prefix 开头的未导入的包，选中时添加 import
+func (r *request) unimportedCandidates() []candidate {
+	if r.prefix == "" {
+		return nil
+	}
+	var candidates []candidate
+	for _, pkg := range r.unimportedPackages(r.prefix) {
+		edit, ok := r.gf.ImportEdit(pkg.path)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, candidate{label: pkg.name, kind: lsp.CIKModule, detail: strconv.Quote(pkg.path), edit: &edit, scope: lowScore})
+	}
+	return candidates
+}
+
+// unimportedMemberCandidates x. 中的 x 没有导入也没有声明时，查找名为 x 的包，补全成员的同时添加 import
+func (r *request) unimportedMemberCandidates(name string) []candidate {
+	for _, pkg := range r.unimportedPackages(name) {
+		if pkg.name != name {
+			continue
+		}
+		edit, ok := r.gf.ImportEdit(pkg.path)
+		if !ok {
+			return nil
+		}
+		members := r.packageMembers(pkg.path, pkg.name)
+		for i := range members {
+			members[i].edit = &edit
+			members[i].scope = lowScore
+		}
+		return members
+	}
+	return nil
+}
+
+// stdPackageBudget 解析过的标准库包占用的内存上限
+const stdPackageBudget = 64 << 20
+
+// sourcePackages 按路径和构建配置缓存解析过的标准库包，标准库的源码不会变化，超过上限时淘汰最久没有使用的包
+var sourcePackages = struct {
+	sync.Mutex
+	lru *semantic.LRU[string, *stdSource]
+}{lru: semantic.NewLRU[string](stdPackageBudget, (*stdSource).size)}
+
+// stdSource 解析过的标准库包和源码的字节数
+type stdSource struct {
+	pkg   *file.GoPackage
+	bytes int64
+}
+
+func (s *stdSource) size() int64 {
+	return s.bytes * semantic.CostPerSourceByte
+}
+
+// stdPackage 解析标准库包的源码，索引中没有标准库时使用
+func (r *request) stdPackage(path, name string) *file.GoPackage {
+	key := fmt.Sprint(path, r.c.Config.Build)
+	sourcePackages.Lock()
+	cached, ok := sourcePackages.lru.Get(key)
+	sourcePackages.Unlock()
+	if ok {
+		return cached.pkg
+	}
+
+	// 解析时不持有锁，同时请求同一个包时各自解析，后完成的覆盖先完成的
+	source := &stdSource{pkg: &file.GoPackage{Name: name}}
+	dir := file.StdPackageDir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return source.pkg
+	}
+	for _, entry := range entries {
+		filename := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(filename) != ".go" || strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		gf, err := file.ParseGoCode(filename, code)
+		if err != nil || gf.File.Name.Name != name || !r.c.Config.Build.Match(gf.Constraint) {
+			continue
+		}
+		source.pkg.Files = append(source.pkg.Files, gf)
+		source.bytes += int64(len(code))
+	}
+	sourcePackages.Lock()
+	sourcePackages.lru.Add(key, source)
+	sourcePackages.Unlock()
+	return source.pkg
+}
+
+// stdMembers 标准库包的导出成员
+func (r *request) stdMembers(path, name string) []candidate {
+	var candidates []candidate
+	for _, gf := range r.stdPackage(path, name).Files {
+		for _, decl := range gf.Scope.Decls {
+			if decl.Type == file.DeclSpecTypeImport || !token.IsExported(decl.Name) {
+				continue
+			}
+			cand := r.declCandidate(gf, decl)
+			cand.typ = qualifiedType(cand.typ, name)
+			candidates = append(candidates, cand)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,58 @@
+package completion
+
+import (
+	"testing"
+)
+
+func TestUnimportedCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		label   string
+		detail  string
+		imports string // 为空时不应该有这个候选项
+	}{
+		{"package", "func f() {\n\tstrin" + cursorMark + "\n}", "strings", `"strings"`, "\n\nimport \"strings\""},
+		{"nested package", "func f() {\n\tjso" + cursorMark + "\n}", "json", `"encoding/json"`, "\n\nimport \"encoding/json\""},
+		{"added to group", "import (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc f() {\n\tstrin" + cursorMark + "\n}", "strings", `"strings"`, "\t\"strings\"\n"},
+		{"member", "func f() {\n\tstrings.ToUp" + cursorMark + "\n}", "ToUpper", "", "\n\nimport \"strings\""},
+		{"already imported", "import \"strings\"\n\nfunc f() {\n\tstrin" + cursorMark + "\n}", "strings", "", ""},
+		{"shadowed", "func f(strings int) {\n\tstrin" + cursorMark + "\n}", "strings", "", ""},
+		{"builtin", "func f() {\n\tbuilti" + cursorMark + "\n}", "builtin", "", ""},
+		{"internal", "func f() {\n\tbytealg" + cursorMark + "\n}", "bytealg", "", ""},
+	}
+	dir := writeModule(t, map[string]string{})
+	c := testService(dir, false)
+	for _, tt := range tests {
+		list := complete(t, c, dir, "m.go", "package m\n\n"+tt.code+"\n")
+		var edits []string
+		var detail string
+		found := false
+		for _, item := range list.Items {
+			if item.Label != tt.label || len(item.AdditionalTextEdits) == 0 {
+				continue
+			}
+			found = true
+			detail = item.Detail
+			for _, edit := range item.AdditionalTextEdits {
+				edits = append(edits, edit.NewText)
+			}
+		}
+		if tt.imports == "" {
+			if found {
+				t.Errorf("%s: unexpected %s with import edits %q", tt.name, tt.label, edits)
+			}
+			continue
+		}
+		if !found {
+			t.Errorf("%s: %s with an import edit not found", tt.name, tt.label)
+			continue
+		}
+		if len(edits) != 1 || edits[0] != tt.imports {
+			t.Errorf("%s: import edits = %q, want %q", tt.name, edits, tt.imports)
+		}
+		if tt.detail != "" && detail != tt.detail {
+			t.Errorf("%s: detail = %q, want %q", tt.name, detail, tt.detail)
+		}
+	}
+}
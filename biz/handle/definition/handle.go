@@ -0,0 +1,27 @@
+package definition
+
+import (
+	"context"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/biz/handle/symbol"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"pkg.nimblebun.works/go-lsp"
+)
+
+func Handle(ctx context.Context, c *engine.LspService, params *lsp.DefinitionParams) ([]lsp.Location, error) {
+	s, err := symbol.Find(c, params.TextDocumentPositionParams)
+	if err != nil || s == nil || s.Position.Filename == "" {
+		return nil, err
+	}
+	// token.Position 的行列从 1 开始，列按字节计算，lsp 的列按 utf-16 编码单元计算
+	uri := file.PathToURI(s.Position.Filename)
+	start := lsp.Position{Line: s.Position.Line - 1, Character: s.Position.Column - 1}
+	if code, err := document.Read(uri); err == nil {
+		start.Character = file.UTF16Column(code, start.Line, start.Character)
+	}
+	return []lsp.Location{{
+		URI:   lsp.DocumentURI(uri),
+		Range: lsp.Range{Start: start, End: start},
+	}}, nil
+}
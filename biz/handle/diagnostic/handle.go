@@ -0,0 +1,65 @@
+package diagnostic
+
+import (
+	"context"
+	"fmt"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/scanner"
+	"os"
+	"pkg.nimblebun.works/go-lsp"
+)
+
+// 诊断的来源
+const (
+	sourceSyntax    = "syntax"
+	sourceStructTag = "structtag"
+)
+
+// Check 解析文件内容并发布语法错误，没有错误时清空客户端之前的诊断
+func Check(ctx context.Context, uri lsp.DocumentURI, code []byte) error {
+	gf, err := file.ParseGoCode(file.URIToPath(string(uri)), code)
+	if err != nil {
+		return err
+	}
+	return Publish(ctx, uri, gf)
+}
+
+// CheckFile 从磁盘读取文件后检查，用于保存文件时
+func CheckFile(ctx context.Context, uri lsp.DocumentURI) error {
+	code, err := os.ReadFile(file.URIToPath(string(uri)))
+	if err != nil {
+		return err
+	}
+	return Check(ctx, uri, code)
+}
+
+func Publish(ctx context.Context, uri lsp.DocumentURI, gf *file.GoFile) error {
+	conn := engine.GetRpcConn(ctx)
+	if conn == nil {
+		return fmt.Errorf("publish diagnostics fail: rpc conn is nil")
+	}
+
+	diagnostics := []lsp.Diagnostic{}
+	diagnostics = appendErrors(diagnostics, gf, gf.SyntaxErrors, lsp.DSError, sourceSyntax)
+	diagnostics = appendErrors(diagnostics, gf, gf.CheckTags(), lsp.DSWarning, sourceStructTag)
+	return conn.Notify(ctx, "textDocument/publishDiagnostics", lsp.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	})
+}
+
+func appendErrors(diagnostics []lsp.Diagnostic, gf *file.GoFile, errs scanner.ErrorList, severity lsp.DiagnosticSeverity, source string) []lsp.Diagnostic {
+	for _, e := range errs {
+		// scanner.Error 的行列从 1 开始，列按字节计算
+		line := e.Pos.Line - 1
+		pos := lsp.Position{Line: line, Character: gf.UTF16Column(line, e.Pos.Column-1)}
+		diagnostics = append(diagnostics, lsp.Diagnostic{
+			Range:    lsp.Range{Start: pos, End: pos},
+			Severity: severity,
+			Source:   source,
+			Message:  e.Msg,
+		})
+	}
+	return diagnostics
+}
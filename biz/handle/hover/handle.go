@@ -0,0 +1,26 @@
+package hover
+
+import (
+	"context"
+	"github.com/denstiny/golang-language-server/biz/handle/symbol"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"pkg.nimblebun.works/go-lsp"
+)
+
+func Handle(ctx context.Context, c *engine.LspService, params *lsp.HoverParams) (*lsp.Hover, error) {
+	s, err := symbol.Find(c, params.TextDocumentPositionParams)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	detail := s.Detail
+	if s.Build != "" {
+		// 只在部分平台上定义的符号，显示它属于哪个构建
+		detail = "//go:build " + s.Build + "\n" + detail
+	}
+	return &lsp.Hover{
+		Contents: lsp.MarkupContent{
+			Kind:  lsp.MKMarkdown,
+			Value: "```go\n" + detail + "\n```",
+		},
+	}, nil
+}
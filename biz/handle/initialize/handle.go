@@ -2,18 +2,24 @@ package initialize
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/denstiny/golang-language-server/biz/conts"
 	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/biz/dal/typecheck"
 	"github.com/denstiny/golang-language-server/biz/handle/progress"
 	"github.com/denstiny/golang-language-server/pkg/engine"
 	"github.com/denstiny/golang-language-server/pkg/file"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/mod/modfile"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"pkg.nimblebun.works/go-lsp"
+	"strings"
 	"time"
 )
 
@@ -26,15 +32,15 @@ func Handle(ctx context.Context, c *engine.LspService, params *lsp.InitializePar
 		log.Error().Msg("begin progres: init workspace index error: %s" + err.Error())
 	}
 
-	// TODO: 实现递归遍历project文件，通过 `file` 包解析索引存储到数据库中
-	for _, folder := range params.WorkspaceFolders {
-		err = LoadGoMod(ctx, folder.Name)
+	// 递归遍历工作区中的 go 文件，通过 `file` 包解析索引存储到数据库中
+	for _, root := range c.Config.WorkFolds {
+		modulePath, requires, err := LoadGoMod(ctx, root)
 		if err != nil {
 			log.Error().Msg("load go modules failed: %s" + err.Error())
 			return lsp.InitializeResult{}, err
 		}
 
-		err = LoadGoCodeFile(ctx, folder.Name)
+		err = LoadGoCodeFile(ctx, c, root, modulePath, requires)
 		if err != nil {
 			log.Error().Msg("load go modules failed: %s" + err.Error())
 			return lsp.InitializeResult{}, err
@@ -55,65 +61,153 @@ func Handle(ctx context.Context, c *engine.LspService, params *lsp.InitializePar
 
 func InitializeService(c *engine.LspService, param *lsp.InitializeParams) {
 	// 将初始化信息暂存到service中
+	// Name 只是客户端显示的名称，目录要从 URI 转换
 	for _, fold := range param.WorkspaceFolders {
-		c.Config.WorkFolds = append(c.Config.WorkFolds, fold.Name)
+		c.Config.WorkFolds = append(c.Config.WorkFolds, file.URIToPath(string(fold.URI)))
 	}
 	c.Config.ClientInfo = param.ClientInfo
+	c.Config.SnippetSupport = snippetSupport(param.Capabilities)
+
+	// initializationOptions: {"goos": "linux", "goarch": "amd64", "buildTags": ["integration"]}
+	c.Config.Build = file.DefaultBuildConfig()
+	if param.InitializationOptions != nil {
+		data, err := json.Marshal(param.InitializationOptions)
+		if err == nil {
+			err = json.Unmarshal(data, &c.Config.Build)
+		}
+		if err != nil {
+			log.Error().Msg("parse initializationOptions failed: " + err.Error())
+		}
+	}
+	typecheck.SetBuild(c.Config.Build)
+}
+
+// snippetSupport 读取 textDocument.completion.completionItem.snippetSupport，不依赖客户端能力结构中的字段是否为指针
+func snippetSupport(capabilities interface{}) bool {
+	var caps struct {
+		TextDocument struct {
+			Completion struct {
+				CompletionItem struct {
+					SnippetSupport bool `json:"snippetSupport"`
+				} `json:"completionItem"`
+			} `json:"completion"`
+		} `json:"textDocument"`
+	}
+	data, err := json.Marshal(capabilities)
+	if err != nil {
+		return false
+	}
+	if err = json.Unmarshal(data, &caps); err != nil {
+		return false
+	}
+	return caps.TextDocument.Completion.CompletionItem.SnippetSupport
 }
 
-func LoadGoMod(ctx context.Context, p string) error {
+// LoadGoMod 读取工作区的 go.mod，返回 module 路径和 require 的 module 路径 -> 版本
+func LoadGoMod(ctx context.Context, p string) (string, map[string]string, error) {
 	filePath := filepath.Join(p, "go.mod")
 	if !file.Exists(filePath) {
-		return fmt.Errorf("load go mod err: %v go.mod not found", filePath)
+		return "", nil, fmt.Errorf("load go mod err: %v go.mod not found", filePath)
 	}
 
 	f, err := file.Open(filePath)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 	defer f.Close()
 
 	data, err := io.ReadAll(f)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	fest, err := modfile.Parse(f.Name(), data, nil)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	pacakges, err := cache.GetPackage(fest.Module.Mod.Path, fest.Module.Mod.Version)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 	if pacakges != nil {
 		log.Info().Str("module", pacakges.PackageName).Msg("found pacakges")
 	}
 
-	return nil
+	requires := make(map[string]string, len(fest.Require))
+	for _, req := range fest.Require {
+		requires[req.Mod.Path] = req.Mod.Version
+	}
+	return fest.Module.Mod.Path, requires, nil
 }
 
-func LoadGoCodeFile(ctx context.Context, path string) error {
-	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return LoadGoCodeFile(ctx, path)
+// LoadGoCodeFile 按目录解析工作区中的 go 文件，每个包写入一次索引。
+// vendor、testdata、隐藏目录和嵌套的 module 不属于当前工作区，单个包索引失败时跳过
+func LoadGoCodeFile(ctx context.Context, c *engine.LspService, root string, modulePath string, requires map[string]string) error {
+	return filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.IsDir() {
+			return nil
 		}
-		if filepath.Ext(info.Name()) != ".go" {
-			f, err := file.Open(info.Name())
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-
-			gf, err := file.ParseGoFile(f)
-			if err != nil {
-				log.Error().Msg(err.Error())
-				return err
-			}
-			log.Info().Interface("gf", gf).Msg("parse ok")
+		name := d.Name()
+		if dir != root && (name == "vendor" || name == "testdata" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || file.Exists(filepath.Join(dir, "go.mod"))) {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil
+		}
+		importPath := path.Join(modulePath, filepath.ToSlash(rel))
+		err = indexDir(c, root, dir, importPath, requires)
+		if err != nil {
+			log.Error().Str("dir", dir).Msg("index package failed: " + err.Error())
 		}
 		return nil
 	})
-	return err
+}
+
+// indexDir 解析目录中构建约束成立的非测试文件，写入 import 路径为 importPath 的包的索引和依赖，包和索引属于 workspace
+func indexDir(c *engine.LspService, workspace string, dir string, importPath string, requires map[string]string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var files []*file.GoFile
+	for _, entry := range entries {
+		filename := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(filename) != ".go" || strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		gf, err := file.ParseGoCode(filename, code)
+		if err != nil || gf.File.Name == nil || !c.Config.Build.Match(gf.Constraint) {
+			continue
+		}
+		// 同一个目录中只能有一个包
+		if len(files) > 0 && gf.File.Name.Name != files[0].File.Name.Name {
+			continue
+		}
+		files = append(files, gf)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	pkg := &model.Package{Name: importPath, PackageName: files[0].File.Name.Name, Workspace: workspace}
+	err = cache.IndexPackage(pkg, files, requires)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("package", pkg.Name).Int("files", len(files)).Msg("index package ok")
+	return nil
 }
@@ -0,0 +1,166 @@
+package symbol
+
+import (
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/dal/cache/model"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/biz/dal/typecheck"
+	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"strings"
+)
+
+// Symbol 光标处标识符指向的符号，悬停和跳转定义共用
+type Symbol struct {
+	Detail   string         // go 语法描述，比如 func strings.ToUpper(s string) string
+	Position token.Position // 定义的位置，Filename 为空时未知
+	Build    string         // 定义所在文件的构建约束，比如 linux && amd64
+}
+
+// Find 查找光标处的符号，开启类型检查模式并且包检查成功时使用类型信息，否则回退到语法分析和索引
+func Find(c *engine.LspService, params lsp.TextDocumentPositionParams) (*Symbol, error) {
+	filename := file.URIToPath(string(params.TextDocument.URI))
+	// 使用编辑器中的内容，位置和客户端看到的一致
+	code, err := document.Read(string(params.TextDocument.URI))
+	if err != nil {
+		return nil, err
+	}
+	// lsp 的列按 utf-16 编码单元计算，ast 中按字节计算
+	line := params.Position.Line
+	column := file.ByteColumn(code, line, params.Position.Character)
+
+	if pkg, ok := typecheck.Load(filename); ok {
+		pos := pkg.PosAt(filename, line, column)
+		if obj := pkg.ObjectAt(filename, pos); obj != nil {
+			detail := types.ObjectString(obj, pkg.Qualifier)
+			if v, ok := obj.(*types.Var); ok {
+				if tag := pkg.FieldTag(v); tag != "" {
+					detail += " `" + tag + "`"
+				}
+			}
+			return &Symbol{
+				Detail:   detail,
+				Position: pkg.Position(obj),
+			}, nil
+		}
+	}
+
+	gf, err := cache.ParseGoCode(filename, code)
+	if err != nil {
+		return nil, err
+	}
+	resolver := cache.IndexResolver{Workspace: c.Config.Workspace(filename), Build: &c.Config.Build, File: gf}
+	pos := gf.TokenPos(file.Position{Line: line, Column: column})
+	ident, selector := gf.IdentAt(pos)
+	if ident == nil {
+		return nil, nil
+	}
+	if selector != nil {
+		return findSelector(gf, selector, resolver)
+	}
+	if field := gf.FieldOf(ident); field != nil {
+		return &Symbol{
+			Detail:   file.FieldString(ident.Name, field),
+			Position: gf.FileSet.Position(ident.Pos()),
+		}, nil
+	}
+
+	if decl, _ := gf.DeclOf(ident); decl != nil {
+		s := &Symbol{
+			Detail:   gf.DeclString(decl, resolver),
+			Position: gf.FileSet.Position(decl.Pos),
+		}
+		if gf.Constraint != nil {
+			s.Build = gf.Constraint.String()
+		}
+		return s, nil
+	}
+	// 同一个包中其他文件定义的符号，同一个包的文件在同一个目录中
+	dir := filepath.Dir(filename)
+	return findIndex(cache.IndexFindParams{
+		Keyword:   &ident.Name,
+		Dir:       &dir,
+		Workspace: &resolver.Workspace,
+		Build:     resolver.Build,
+	})
+}
+
+// findSelector 查找 x.Sel 中的 Sel，x 是导入的包时查找包成员，否则推断 x 的类型后查找字段和方法
+func findSelector(gf *file.GoFile, selector *ast.SelectorExpr, resolver cache.IndexResolver) (*Symbol, error) {
+	if x, ok := selector.X.(*ast.Ident); ok {
+		if decl, _ := gf.LookupVisible(x.Name, x.Pos()); decl == nil || decl.Type == file.DeclSpecTypeImport {
+			if spec, ok := gf.ImportByName(x.Name); ok {
+				return findIndex(cache.IndexFindParams{
+					Keyword:     &selector.Sel.Name,
+					PackagePath: &spec.Path,
+					Workspace:   &resolver.Workspace,
+					Build:       resolver.Build,
+				})
+			}
+		}
+	}
+
+	typ := strings.TrimPrefix(first(gf.InferType(selector.X, resolver)), "*")
+	if i := strings.Index(typ, "["); i >= 0 {
+		typ = typ[:i]
+	}
+	if typ == "" {
+		return nil, nil
+	}
+	params := cache.IndexFindParams{
+		Keyword:   &selector.Sel.Name,
+		Workspace: &resolver.Workspace,
+		Build:     resolver.Build,
+	}
+	pkg, name, ok := strings.Cut(typ, ".")
+	if ok {
+		spec, found := gf.ImportByName(pkg)
+		if !found {
+			return nil, nil
+		}
+		params.PackagePath = &spec.Path
+	} else {
+		// 当前包中的类型
+		name = typ
+		dir := filepath.Dir(gf.FileSet.Position(selector.Pos()).Filename)
+		params.Dir = &dir
+	}
+	params.Comparable = &name
+	return findIndex(params)
+}
+
+func findIndex(params cache.IndexFindParams) (*Symbol, error) {
+	indexes, err := cache.FindIndex(params)
+	if err != nil || len(indexes) == 0 {
+		return nil, err
+	}
+	index := indexes[0]
+	// 查找包级别符号时忽略同名的字段和方法
+	for _, i := range indexes {
+		if params.Comparable != nil || i.Type == model.IndexTypeVar || i.Type == model.IndexTypeFunc || i.Type == model.IndexTypeType {
+			index = i
+			break
+		}
+	}
+	return &Symbol{
+		Detail: cache.IndexString(index),
+		Build:  index.Build,
+		Position: token.Position{
+			Filename: index.FilePath,
+			Line:     index.JoinLine,
+			Column:   index.JoinCol,
+		},
+	}, nil
+}
+
+func first(types []string) string {
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
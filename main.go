@@ -1,11 +1,28 @@
 package main
 
 import (
+	"flag"
+	"github.com/denstiny/golang-language-server/biz/command"
+	"github.com/denstiny/golang-language-server/biz/dal/cache"
+	"github.com/denstiny/golang-language-server/biz/dal/typecheck"
 	"github.com/denstiny/golang-language-server/biz/flags"
 	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/rs/zerolog/log"
 )
 
 func main() {
+	flags.Parse()
+	cache.Init()
+	typecheck.Init()
+
+	if flag.NArg() > 0 {
+		err := command.Run(flag.Args())
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		return
+	}
+
 	client := engine.NewClient(RpcHandles())
 	client.SetConfig(engine.Config{
 		ServerPort:      flags.SERVICE_PROT,
@@ -1,6 +1,11 @@
 package engine
 
-import "pkg.nimblebun.works/go-lsp"
+import (
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"path/filepath"
+	"pkg.nimblebun.works/go-lsp"
+	"strings"
+)
 
 type Config struct {
 	ServerPort      int
@@ -8,4 +13,22 @@ type Config struct {
 	ServerConfigDir string
 	Trace           bool
 	ClientInfo      lsp.ClientInfo
+	Build           file.BuildConfig // 目标平台和构建标签，决定哪些文件参与索引和类型检查
+	SnippetSupport  bool             // 客户端支持 snippet 格式的补全项
+}
+
+// Workspace 返回 filename 所属的工作区目录，用于限定索引查询的范围，不属于任何工作区时返回空
+func (c Config) Workspace(filename string) string {
+	var workspace string
+	for _, fold := range c.WorkFolds {
+		rel, err := filepath.Rel(fold, filename)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		// 嵌套的工作区取最近的一个
+		if len(fold) > len(workspace) {
+			workspace = fold
+		}
+	}
+	return workspace
 }
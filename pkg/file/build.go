@@ -0,0 +1,195 @@
+package file
+
+import (
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/scanner"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// 和 go/build 中的 syslist 保持一致
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "netbsd": true,
+		"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+	}
+	unixOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "linux": true, "netbsd": true, "openbsd": true, "solaris": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+		"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true,
+		"riscv64": true, "s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// BuildConfig 选择文件时使用的目标平台和构建标签，来自客户端的 initializationOptions
+type BuildConfig struct {
+	GOOS   string   `json:"goos"`
+	GOARCH string   `json:"goarch"`
+	Tags   []string `json:"buildTags"`
+}
+
+// DefaultBuildConfig 当前平台，不带额外的构建标签
+func DefaultBuildConfig() BuildConfig {
+	return BuildConfig{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+}
+
+// Match 判断构建约束在该配置下是否成立，nil 表示没有约束
+func (b BuildConfig) Match(expr constraint.Expr) bool {
+	if expr == nil {
+		return true
+	}
+	return expr.Eval(b.matchTag)
+}
+
+// MatchString 判断索引中保存的构建约束是否成立，空字符串表示没有约束，格式错误的约束不成立
+func (b BuildConfig) MatchString(expr string) bool {
+	if expr == "" {
+		return true
+	}
+	x, err := constraint.Parse("//go:build " + expr)
+	if err != nil {
+		return false
+	}
+	return b.Match(x)
+}
+
+func (b BuildConfig) matchTag(tag string) bool {
+	goos, goarch := b.GOOS, b.GOARCH
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	switch {
+	case tag == goos || tag == goarch:
+		return true
+	case tag == "linux" && goos == "android",
+		tag == "solaris" && goos == "illumos",
+		tag == "darwin" && goos == "ios":
+		return true
+	case tag == "unix":
+		return unixOS[goos]
+	case tag == "gc":
+		return true
+	case tag == "cgo":
+		// 只有目标平台是当前平台时才能确定 cgo 是否可用
+		if goos == runtime.GOOS && goarch == runtime.GOARCH && build.Default.CgoEnabled {
+			return true
+		}
+	}
+	return slices.Contains(build.Default.ReleaseTags, tag) || slices.Contains(b.Tags, tag)
+}
+
+// FileConstraint 返回文件的构建约束，包括 //go:build 行和文件名中的 GOOS/GOARCH 后缀，没有约束时返回 nil
+func FileConstraint(filename string, file *ast.File) constraint.Expr {
+	var exprs []constraint.Expr
+	if x := nameConstraint(filename); x != nil {
+		exprs = append(exprs, x)
+	}
+	if x := commentConstraint(file); x != nil {
+		exprs = append(exprs, x)
+	}
+	if len(exprs) == 0 {
+		return nil
+	}
+	x := exprs[0]
+	for _, y := range exprs[1:] {
+		x = &constraint.AndExpr{X: x, Y: y}
+	}
+	return x
+}
+
+// commentConstraint 解析 package 之前的 //go:build 行，没有 //go:build 时使用旧的 // +build 行
+func commentConstraint(file *ast.File) constraint.Expr {
+	if file == nil {
+		return nil
+	}
+	var goBuild, plusBuild constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		// 紧挨着 package 的是包文档，构建约束后面必须有空行
+		if group == file.Doc {
+			continue
+		}
+		for _, c := range group.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				if x, err := constraint.Parse(c.Text); err == nil && goBuild == nil {
+					goBuild = x
+				}
+			case constraint.IsPlusBuild(c.Text):
+				x, err := constraint.Parse(c.Text)
+				if err != nil {
+					continue
+				}
+				if plusBuild == nil {
+					plusBuild = x
+				} else {
+					plusBuild = &constraint.AndExpr{X: plusBuild, Y: x}
+				}
+			}
+		}
+	}
+	if goBuild != nil {
+		return goBuild
+	}
+	return plusBuild
+}
+
+// buildLineErrors package 之前格式错误的 //go:build 行，go 命令会拒绝构建这样的文件
+func (g *GoFile) buildLineErrors() scanner.ErrorList {
+	var errs scanner.ErrorList
+	if g.File == nil {
+		return nil
+	}
+	for _, group := range g.File.Comments {
+		if group.Pos() >= g.File.Package {
+			break
+		}
+		if group == g.File.Doc {
+			continue
+		}
+		for _, c := range group.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			if _, err := constraint.Parse(c.Text); err != nil {
+				errs.Add(g.FileSet.Position(c.Pos()), "parsing //go:build line: "+err.Error())
+			}
+		}
+	}
+	return errs
+}
+
+// nameConstraint 解析文件名中的 _GOOS、_GOARCH、_GOOS_GOARCH 后缀，规则和 go/build 相同
+func nameConstraint(filename string) constraint.Expr {
+	name := strings.TrimSuffix(filepath.Base(filename), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	// 第一个下划线之前的部分不算后缀，比如 linux.go 没有约束
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return nil
+	}
+	l := strings.Split(name[i:], "_")
+	n := len(l)
+	if n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]] {
+		return &constraint.AndExpr{X: &constraint.TagExpr{Tag: l[n-2]}, Y: &constraint.TagExpr{Tag: l[n-1]}}
+	}
+	if knownOS[l[n-1]] || knownArch[l[n-1]] {
+		return &constraint.TagExpr{Tag: l[n-1]}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package file
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileConstraint(t *testing.T) {
+	tests := []struct {
+		filename string
+		header   string
+		want     string
+	}{
+		{"foo.go", "", ""},
+		{"linux.go", "", ""},
+		{"foo_linux.go", "", "linux"},
+		{"foo_windows_test.go", "", "windows"},
+		{"foo_linux_arm64.go", "", "linux && arm64"},
+		{"foo_arm64.go", "", "arm64"},
+		{"foo_bar.go", "", ""},
+		{"foo.go", "//go:build ignore\n\n", "ignore"},
+		{"foo_linux.go", "//go:build cgo && !race\n\n", "linux && cgo && !race"},
+		{"foo.go", "// +build linux darwin\n// +build amd64\n\n", "(linux || darwin) && amd64"},
+		{"foo.go", "//go:build unix\n// +build linux\n\n", "unix"},
+		{"foo.go", "// 不是构建约束\n//go:build ignore\n", ""},
+	}
+	for _, tt := range tests {
+		gf, err := ParseGoCode(tt.filename, []byte(tt.header+"package foo\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if gf.Constraint != nil {
+			got = gf.Constraint.String()
+		}
+		if got != tt.want {
+			t.Errorf("%s %q: got %q, want %q", tt.filename, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestBuildConfigMatch(t *testing.T) {
+	linux := BuildConfig{GOOS: "linux", GOARCH: "amd64"}
+	android := BuildConfig{GOOS: "android", GOARCH: "arm64", Tags: []string{"integration"}}
+	windows := BuildConfig{GOOS: "windows", GOARCH: "amd64"}
+
+	tests := []struct {
+		config BuildConfig
+		expr   string
+		want   bool
+	}{
+		{linux, "", true},
+		{linux, "linux", true},
+		{linux, "linux && amd64", true},
+		{linux, "linux && arm64", false},
+		{linux, "windows", false},
+		{linux, "unix", true},
+		{linux, "ignore", false},
+		{linux, "go1.1", true},
+		{linux, "integration", false},
+		{android, "linux", true},
+		{android, "unix && arm64", true},
+		{android, "integration", true},
+		{windows, "unix", false},
+		{windows, "!windows", false},
+		{windows, "gc", true},
+		{linux, "linux &&", false},
+		{linux, "(linux", false},
+	}
+	for _, tt := range tests {
+		if got := tt.config.MatchString(tt.expr); got != tt.want {
+			t.Errorf("%s/%s %q: got %v, want %v", tt.config.GOOS, tt.config.GOARCH, tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestBuildLineErrors(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int // 报告错误的行
+	}{
+		{"//go:build linux\n\n", nil},
+		{"//go:build linux &&\n\n", []int{1}},
+		{"// +build linux\n//go:build (linux\n\n", []int{2}},
+		{"//go:build linux &&\npackage foo\n", nil},
+	}
+	for _, tt := range tests {
+		code := tt.header
+		if !strings.Contains(code, "package") {
+			code += "package foo\n"
+		}
+		gf, err := ParseGoCode("foo.go", []byte(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []int
+		for _, e := range gf.SyntaxErrors {
+			lines = append(lines, e.Pos.Line)
+		}
+		if !slices.Equal(lines, tt.want) {
+			t.Errorf("%q: errors at lines %v, want %v", tt.header, lines, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package file
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// CompositeLit 光标所在的复合字面量
+type CompositeLit struct {
+	Type       string   // 字面量的类型，省略类型的元素从外层字面量推断，&T{} 省略时为 T
+	Keys       []string // 已经设置的字段名称
+	Positional bool     // 已经有不带字段名称的元素
+	Multiline  bool     // 大括号不在同一行
+	Indent     string   // 光标所在行开头的空白，多行的字面量中每个字段一行时使用
+}
+
+// CompositeLitAt pos 在复合字面量的大括号中、可以输入字段名称的位置时返回最内层的字面量，
+// pos 在元素的值中时返回 false
+func (g *GoFile) CompositeLitAt(pos token.Pos) (CompositeLit, bool) {
+	if g.File == nil {
+		return CompositeLit{}, false
+	}
+	var path []*ast.CompositeLit
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || n.End().IsValid() && pos > n.End() {
+			return false
+		}
+		if lit, ok := n.(*ast.CompositeLit); ok && lit.Lbrace < pos && (!lit.Rbrace.IsValid() || pos <= lit.Rbrace) {
+			path = append(path, lit)
+		}
+		return true
+	})
+	if len(path) == 0 {
+		return CompositeLit{}, false
+	}
+
+	lit := path[len(path)-1]
+	result := CompositeLit{
+		Multiline: g.position(lit.Lbrace).Line != g.position(lit.Rbrace).Line,
+		Indent:    g.LineIndent(pos),
+	}
+	for _, elt := range lit.Elts {
+		inside := elt.Pos() <= pos && pos <= elt.End()
+		switch e := elt.(type) {
+		case *ast.KeyValueExpr:
+			if inside && pos > e.Colon {
+				return CompositeLit{}, false
+			}
+			if key, ok := e.Key.(*ast.Ident); ok && !inside {
+				result.Keys = append(result.Keys, key.Name)
+			}
+		case *ast.Ident:
+			// 正在输入的字段名称
+			if !inside {
+				result.Positional = true
+			}
+		case *ast.BadExpr:
+		default:
+			if inside {
+				return CompositeLit{}, false
+			}
+			result.Positional = true
+		}
+	}
+	result.Type = compositeLitType(path)
+	return result, true
+}
+
+// LineIndent pos 所在行开头的空白
+func (g *GoFile) LineIndent(pos token.Pos) string {
+	start, end, ok := g.text.lineRange(g.position(pos).Line)
+	if !ok {
+		return ""
+	}
+	i := start
+	for i < end && (g.text.data[i] == ' ' || g.text.data[i] == '\t') {
+		i++
+	}
+	return string(g.text.data[start:i])
+}
+
+// compositeLitType 最内层字面量的类型，[]T{{...}} 和 map[K]V{k: {...}} 中省略的类型是外层的元素类型
+func compositeLitType(path []*ast.CompositeLit) string {
+	lit := path[len(path)-1]
+	if lit.Type != nil {
+		return getTypeString(lit.Type)
+	}
+	if len(path) < 2 {
+		return ""
+	}
+	outer := compositeLitType(path[:len(path)-1])
+	// 省略的 &T{} 写作 {}
+	return strings.TrimPrefix(elemType(outer), "*")
+}
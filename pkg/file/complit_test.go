@@ -0,0 +1,47 @@
+package file
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompositeLitAt(t *testing.T) {
+	tests := []struct {
+		expr string
+		want *CompositeLit // nil 表示不在可以输入字段名称的位置
+	}{
+		{"model.Index{|}", &CompositeLit{Type: "model.Index", Indent: "\t"}},
+		{"model.Index{ Na| }", &CompositeLit{Type: "model.Index", Indent: "\t"}},
+		{"&User{Name: \"a\", |}", &CompositeLit{Type: "User", Keys: []string{"Name"}, Indent: "\t"}},
+		{"User{Name: \"a\", A|, Age: 1}", &CompositeLit{Type: "User", Keys: []string{"Name", "Age"}, Indent: "\t"}},
+		{"User{\n\t\t|\n\t}", &CompositeLit{Type: "User", Multiline: true, Indent: "\t\t"}},
+		{"[]*User{{Name: \"a\"}, {|}}", &CompositeLit{Type: "User", Indent: "\t"}},
+		{"map[string]User{\"a\": {|}}", &CompositeLit{Type: "User", Indent: "\t"}},
+		{"User{\"a\", |}", &CompositeLit{Type: "User", Positional: true, Indent: "\t"}},
+		{"User{Name: |}", nil},
+		{"User{Name: na|}", nil},
+		{"User{Name: f(|)}", nil},
+		{"User{}|", nil},
+		{"f(|)", nil},
+	}
+	for _, tt := range tests {
+		code := "package p\n\nfunc f() {\n\t_ = " + tt.expr + "\n}\n"
+		cursor := strings.Index(code, "|")
+		code = code[:cursor] + code[cursor+1:]
+		gf, err := ParseGoCode("p.go", []byte(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := gf.CompositeLitAt(gf.FileSet.File(gf.File.Pos()).Pos(cursor))
+		if tt.want == nil {
+			if ok {
+				t.Errorf("CompositeLitAt(%q) = %+v, want false", tt.expr, got)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, *tt.want) {
+			t.Errorf("CompositeLitAt(%q) = %+v, %v, want %+v", tt.expr, got, ok, *tt.want)
+		}
+	}
+}
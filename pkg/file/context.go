@@ -0,0 +1,422 @@
+package file
+
+import (
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// CursorContextKind 光标处正在输入的标识符所在的语法位置
+type CursorContextKind int
+
+const (
+	ContextUnknown    CursorContextKind = iota
+	ContextFileStart                    // package 子句之前
+	ContextTopLevel                     // 包级别声明的开头
+	ContextStatement                    // 函数体中语句的开头
+	ContextSwitchBody                   // switch、select 的大括号中，子句之外
+	ContextType                         // 类型表达式
+	ContextExpression                   // 其他表达式
+	ContextName                         // 声明的名称
+	ContextString                       // 字符串和字符字面量
+	ContextComment                      // 注释
+)
+
+// CursorContext 光标处的语法上下文，标志位只在语句开头和表达式中有意义
+type CursorContext struct {
+	Kind        CursorContextKind
+	InLoop      bool // 在 for 循环体中，可以 break、continue
+	InSwitch    bool // 在 switch 的子句中，可以 break
+	InSelect    bool // 在 select 的子句中，可以 break
+	CaseEnd     bool // 表达式 switch 中非最后一个 case 的最后一条语句，可以 fallthrough
+	AfterIf     bool // 紧跟在没有 else 的 if 的 } 后面，只能写 else
+	ForHeader   bool // 在 for 的初始化、条件语句中，可以 range
+	ImportsOnly bool // 前面只有 import 声明，还可以继续 import
+}
+
+// CursorContext 判断光标前正在输入的标识符处在什么语法位置。
+// 先按词法判断是否在注释、字符串中，再在部分恢复的 ast 上根据父节点判断
+func (g *GoFile) CursorContext(pos Position) CursorContext {
+	start, end, ok := g.text.lineRange(pos.Line)
+	if !ok || pos.Column < 0 {
+		return CursorContext{}
+	}
+	cursor := min(start+pos.Column, end)
+	word := cursor
+	for word > start && isIdentByte(g.text.data[word-1]) {
+		word--
+	}
+
+	if kind := g.lexicalContext(word, cursor); kind != ContextUnknown {
+		return CursorContext{Kind: kind}
+	}
+	if g.File == nil || g.FileSet == nil {
+		return CursorContext{}
+	}
+	tf := g.FileSet.File(g.File.Pos())
+	if tf == nil {
+		return CursorContext{}
+	}
+	// 换行后 } 的后面会自动插入分号，只有和 if 的 } 在同一行才能写 else
+	if g.ifBefore(tf, word) != nil {
+		return CursorContext{Kind: ContextStatement, AfterIf: true}
+	}
+	return g.syntaxContext(tf.Pos(word))
+}
+
+// ifBefore 同一行中 word 前面是没有 else 的 if 语句的 }，返回这个 if 语句
+func (g *GoFile) ifBefore(tf *token.File, word int) *ast.IfStmt {
+	i := word
+	for i > 0 && (g.text.data[i-1] == ' ' || g.text.data[i-1] == '\t') {
+		i--
+	}
+	if i == 0 || g.text.data[i-1] != '}' {
+		return nil
+	}
+	rbrace := tf.Pos(i - 1)
+	var found *ast.IfStmt
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || found != nil || rbrace < n.Pos() || rbrace >= n.End() {
+			return false
+		}
+		if ifStmt, ok := n.(*ast.IfStmt); ok && ifStmt.Else == nil && ifStmt.Body.Rbrace == rbrace {
+			found = ifStmt
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// lexicalContext 扫描光标之前的 token，判断光标是否在注释、字符串中，或者前面还没有任何 token
+func (g *GoFile) lexicalContext(word, cursor int) CursorContextKind {
+	fset := token.NewFileSet()
+	tf := fset.AddFile("", -1, len(g.text.data))
+	var s scanner.Scanner
+	// 输入过程中的代码通常有错误，忽略错误继续扫描
+	s.Init(tf, g.text.data, func(token.Position, string) {}, scanner.ScanComments)
+
+	empty := true
+	for {
+		p, tok, lit := s.Scan()
+		off := tf.Offset(p)
+		if tok == token.EOF || off >= cursor {
+			break
+		}
+		switch tok {
+		case token.COMMENT:
+			end := off + len(lit)
+			// 行注释一直到行尾，块注释没有结束时一直到文件末尾
+			if strings.HasPrefix(lit, "//") || !strings.HasSuffix(lit, "*/") || len(lit) < 4 {
+				if cursor <= end {
+					return ContextComment
+				}
+			} else if cursor < end {
+				return ContextComment
+			}
+			continue
+		case token.STRING, token.CHAR:
+			end := off + len(lit)
+			if cursor < end || cursor == end && !closedLiteral(lit) {
+				return ContextString
+			}
+		case token.SEMICOLON:
+			// 换行时自动插入的分号
+			if lit == "\n" {
+				continue
+			}
+		}
+		if off < word {
+			empty = false
+		}
+	}
+	if empty {
+		return ContextFileStart
+	}
+	return ContextUnknown
+}
+
+// closedLiteral 字符串或字符字面量是否有结束的引号
+func closedLiteral(lit string) bool {
+	if len(lit) < 2 || lit[len(lit)-1] != lit[0] {
+		return false
+	}
+	if lit[0] == '`' {
+		return true
+	}
+	// 结束的引号前面有奇数个反斜杠时是转义的引号
+	n := 0
+	for i := len(lit) - 2; i > 0 && lit[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 0
+}
+
+// syntaxContext 根据包含 pos 的节点路径判断语法位置
+func (g *GoFile) syntaxContext(pos token.Pos) CursorContext {
+	path := []ast.Node{g.File}
+	var stack []ast.Node // 正在遍历的节点，stack[i] 是 stack[i+1] 的父节点
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if n != g.File {
+			// 同一个父节点下前面的子节点已经包含 pos，比如 FuncDecl 的 Name 和从 func 开始的 Type
+			if len(path) > len(stack) || pos < n.Pos() || pos >= n.End() {
+				return false
+			}
+			path = append(path, n)
+		}
+		stack = append(stack, n)
+		return true
+	})
+
+	ctx := CursorContext{}
+	inFunc := false
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			inFunc = inFunc || n.Body != nil && n.Body.Pos() <= pos
+		case *ast.FuncLit:
+			// 函数字面量中不能跳出外层的循环
+			if n.Body != nil && n.Body.Pos() <= pos {
+				inFunc = true
+				ctx = CursorContext{}
+			}
+		case *ast.ForStmt:
+			if n.Body != nil && n.Body.Pos() <= pos {
+				ctx.InLoop = true
+			} else {
+				ctx.ForHeader = true
+			}
+		case *ast.RangeStmt:
+			if n.Body != nil && n.Body.Pos() <= pos {
+				ctx.InLoop = true
+			}
+		case *ast.CaseClause:
+			if n.Colon < pos {
+				ctx.InSwitch = true
+			}
+		case *ast.CommClause:
+			if n.Colon < pos {
+				ctx.InSelect = true
+			}
+		case *ast.BlockStmt:
+			// 进入新的语句块后，外层 for 子句中的位置不再有效
+			ctx.ForHeader = false
+		}
+	}
+
+	n := path[len(path)-1]
+	switch n := n.(type) {
+	case *ast.File, *ast.BadDecl:
+		ctx.Kind = ContextTopLevel
+		ctx.ImportsOnly = g.importsOnly(pos)
+		return ctx
+	case *ast.BlockStmt:
+		if len(path) > 1 {
+			switch path[len(path)-2].(type) {
+			case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				// 子句最后一条语句后面的空行不在子句的范围内，但仍然属于这个子句
+				switch clause := clauseBefore(n.List, pos).(type) {
+				case *ast.CaseClause:
+					ctx.InSwitch = true
+					g.statementContext(&ctx, clause.Body, append(path, clause), pos)
+				case *ast.CommClause:
+					ctx.InSelect = true
+					g.statementContext(&ctx, clause.Body, append(path, clause), pos)
+				default:
+					ctx.Kind = ContextSwitchBody
+				}
+				return ctx
+			}
+		}
+		g.statementContext(&ctx, n.List, path, pos)
+		return ctx
+	case *ast.CaseClause:
+		if n.Colon < pos {
+			g.statementContext(&ctx, n.Body, path, pos)
+			return ctx
+		}
+	case *ast.CommClause:
+		if n.Colon < pos {
+			g.statementContext(&ctx, n.Body, path, pos)
+			return ctx
+		}
+	case ast.Stmt:
+		// 正在输入语句开头的关键字，比如 if、for，解析出的是还不完整的语句
+		if n.Pos() == pos && len(path) > 1 {
+			switch c := path[len(path)-2].(type) {
+			case *ast.BlockStmt:
+				g.statementContext(&ctx, c.List, path[:len(path)-1], pos)
+				return ctx
+			case *ast.CaseClause:
+				g.statementContext(&ctx, c.Body, path[:len(path)-1], pos)
+				return ctx
+			case *ast.CommClause:
+				g.statementContext(&ctx, c.Body, path[:len(path)-1], pos)
+				return ctx
+			}
+		}
+	case *ast.Ident, *ast.BadExpr:
+		if n.Pos() != pos {
+			break
+		}
+		i := len(path) - 2
+		// *T 中的 T 和 *T 处在相同的位置
+		for i > 0 {
+			if _, ok := path[i].(*ast.StarExpr); !ok {
+				break
+			}
+			i--
+		}
+		child := path[i+1]
+		switch parent := path[i].(type) {
+		case *ast.ExprStmt:
+			container := path[i-1]
+			switch c := container.(type) {
+			case *ast.BlockStmt:
+				g.statementContext(&ctx, c.List, path[:i], pos)
+			case *ast.CaseClause:
+				g.statementContext(&ctx, c.Body, path[:i], pos)
+			case *ast.CommClause:
+				g.statementContext(&ctx, c.Body, path[:i], pos)
+			}
+			return ctx
+		case *ast.ValueSpec, *ast.TypeSpec, *ast.FuncDecl, *ast.Field:
+			if isDeclName(parent, child) {
+				ctx.Kind = ContextName
+				return ctx
+			}
+		case *ast.CaseClause:
+			// 类型 switch 的 case 后面是类型
+			if _, ok := path[i-2].(*ast.TypeSwitchStmt); ok {
+				ctx.Kind = ContextType
+				return ctx
+			}
+		}
+		if isTypePosition(path[i], child) {
+			ctx.Kind = ContextType
+			return ctx
+		}
+	}
+	if !inFunc && len(path) > 1 {
+		if _, ok := path[1].(*ast.FuncDecl); ok {
+			// 函数签名中
+			ctx.Kind = ContextType
+			return ctx
+		}
+	}
+	ctx.Kind = ContextExpression
+	return ctx
+}
+
+// statementContext 光标在 stmts 所在语句列表中一条语句的开头，path 以包含语句列表的节点结尾
+func (g *GoFile) statementContext(ctx *CursorContext, stmts []ast.Stmt, path []ast.Node, pos token.Pos) {
+	ctx.Kind = ContextStatement
+	ctx.ForHeader = false
+
+	last := true
+	for _, stmt := range stmts {
+		if stmt.Pos() > pos {
+			last = false
+		}
+	}
+	// fallthrough 只能是表达式 switch 中最后一个 case 以外的子句的最后一条语句
+	if len(path) < 3 || !last {
+		return
+	}
+	clause, ok := path[len(path)-1].(*ast.CaseClause)
+	if !ok {
+		return
+	}
+	body, ok := path[len(path)-2].(*ast.BlockStmt)
+	if _, isSwitch := path[len(path)-3].(*ast.SwitchStmt); !ok || !isSwitch {
+		return
+	}
+	ctx.CaseEnd = len(body.List) > 0 && body.List[len(body.List)-1] != clause
+}
+
+// clauseBefore 返回 switch、select 的大括号中冒号在 pos 之前的最后一个子句，没有时返回 nil
+func clauseBefore(clauses []ast.Stmt, pos token.Pos) ast.Stmt {
+	var found ast.Stmt
+	for _, stmt := range clauses {
+		switch clause := stmt.(type) {
+		case *ast.CaseClause:
+			if clause.Colon < pos {
+				found = clause
+			}
+		case *ast.CommClause:
+			if clause.Colon < pos {
+				found = clause
+			}
+		}
+	}
+	return found
+}
+
+// importsOnly 光标之前的声明是否都是 import
+func (g *GoFile) importsOnly(pos token.Pos) bool {
+	for _, decl := range g.File.Decls {
+		if decl.Pos() >= pos {
+			break
+		}
+		if gen, ok := decl.(*ast.GenDecl); !ok || gen.Tok != token.IMPORT {
+			return false
+		}
+	}
+	return true
+}
+
+// isDeclName child 是否为 parent 声明的名称
+func isDeclName(parent, child ast.Node) bool {
+	var names []*ast.Ident
+	switch p := parent.(type) {
+	case *ast.ValueSpec:
+		names = p.Names
+	case *ast.Field:
+		names = p.Names
+	case *ast.TypeSpec:
+		return p.Name == child
+	case *ast.FuncDecl:
+		return p.Name == child
+	}
+	for _, name := range names {
+		if name == child {
+			return true
+		}
+	}
+	return false
+}
+
+// isTypePosition child 在 parent 中是否处在类型的位置
+func isTypePosition(parent, child ast.Node) bool {
+	switch p := parent.(type) {
+	case *ast.Field:
+		return p.Type == child
+	case *ast.ValueSpec:
+		return p.Type == child
+	case *ast.TypeSpec:
+		return p.Type == child
+	case *ast.ArrayType:
+		return p.Elt == child
+	case *ast.MapType:
+		return p.Key == child || p.Value == child
+	case *ast.ChanType:
+		return p.Value == child
+	case *ast.Ellipsis:
+		return p.Elt == child
+	case *ast.CompositeLit:
+		return p.Type == child
+	case *ast.TypeAssertExpr:
+		return p.Type == child
+	case *ast.CallExpr:
+		// make、new 的第一个参数是类型
+		if fun, ok := p.Fun.(*ast.Ident); ok && (fun.Name == "make" || fun.Name == "new") {
+			return len(p.Args) > 0 && p.Args[0] == child
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCursorContext(t *testing.T) {
+	tests := []struct {
+		name string
+		code string // | 为光标位置
+		want CursorContext
+	}{
+		{"empty file", "pa|", CursorContext{Kind: ContextFileStart}},
+		{"after build tag", "//go:build linux\n\npa|", CursorContext{Kind: ContextFileStart}},
+		{"top level", "package p\n\nfu|", CursorContext{Kind: ContextTopLevel, ImportsOnly: true}},
+		{"after func", "package p\n\nfunc f() {}\n\nim|", CursorContext{Kind: ContextTopLevel}},
+		{"line comment", "package p\n\n// fo|\nfunc f() {}", CursorContext{Kind: ContextComment}},
+		{"block comment", "package p\n\nfunc f() { /* re| */ }", CursorContext{Kind: ContextComment}},
+		{"string", "package p\n\nvar s = \"fo|\"", CursorContext{Kind: ContextString}},
+		{"unterminated string", "package p\n\nfunc f() {\n\ts := \"fo|\n}", CursorContext{Kind: ContextString}},
+		{"statement", "package p\n\nfunc f() {\n\tre|\n}", CursorContext{Kind: ContextStatement}},
+		{"empty statement", "package p\n\nfunc f() {\n\t|\n}", CursorContext{Kind: ContextStatement}},
+		{"for body", "package p\n\nfunc f() {\n\tfor {\n\t\tco|\n\t}\n}", CursorContext{Kind: ContextStatement, InLoop: true}},
+		{"func lit in loop", "package p\n\nfunc f() {\n\tfor {\n\t\tgo func() {\n\t\t\tbr|\n\t\t}()\n\t}\n}", CursorContext{Kind: ContextStatement}},
+		{"for header", "package p\n\nfunc f() {\n\tfor i := ra| {\n\t}\n}", CursorContext{Kind: ContextExpression, ForHeader: true}},
+		{"switch body", "package p\n\nfunc f() {\n\tswitch x {\n\tca|\n\t}\n}", CursorContext{Kind: ContextSwitchBody}},
+		{"case end", "package p\n\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\tfa|\n\tcase 2:\n\t}\n}", CursorContext{Kind: ContextStatement, InSwitch: true, CaseEnd: true}},
+		{"last case", "package p\n\nfunc f() {\n\tswitch x {\n\tcase 1:\n\tcase 2:\n\t\tfa|\n\t}\n}", CursorContext{Kind: ContextStatement, InSwitch: true}},
+		{"case not end", "package p\n\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\tfa|\n\t\tg()\n\tcase 2:\n\t}\n}", CursorContext{Kind: ContextStatement, InSwitch: true}},
+		{"type switch", "package p\n\nfunc f() {\n\tswitch x.(type) {\n\tcase 1:\n\t\tfa|\n\tcase 2:\n\t}\n}", CursorContext{Kind: ContextStatement, InSwitch: true}},
+		{"empty case line", "package p\n\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\t|\n\tcase 2:\n\t}\n}", CursorContext{Kind: ContextStatement, InSwitch: true, CaseEnd: true}},
+		{"after case body", "package p\n\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\tg()\n\t\t|\n\t}\n}", CursorContext{Kind: ContextStatement, InSwitch: true}},
+		{"empty select line", "package p\n\nfunc f() {\n\tselect {\n\tcase <-c:\n\t\t|\n\t}\n}", CursorContext{Kind: ContextStatement, InSelect: true}},
+		{"keyword prefix", "package p\n\nfunc f() {\n\tif|\n}", CursorContext{Kind: ContextStatement}},
+		{"keyword prefix in loop", "package p\n\nfunc f() {\n\tfor {\n\t\tfor|\n\t}\n}", CursorContext{Kind: ContextStatement, InLoop: true}},
+		{"select", "package p\n\nfunc f() {\n\tselect {\n\tcase <-c:\n\t\tbr|\n\t}\n}", CursorContext{Kind: ContextStatement, InSelect: true}},
+		{"after if", "package p\n\nfunc f() {\n\tif x {\n\t} el|\n}", CursorContext{Kind: ContextStatement, AfterIf: true}},
+		{"after if brace", "package p\n\nfunc f() {\n\tif x {\n\t}el|\n}", CursorContext{Kind: ContextStatement, AfterIf: true}},
+		{"after if next line", "package p\n\nfunc f() {\n\tif x {\n\t}\n\tel|\n}", CursorContext{Kind: ContextStatement}},
+		{"after else if", "package p\n\nfunc f() {\n\tif x {\n\t} else if y {\n\t} el|\n}", CursorContext{Kind: ContextStatement, AfterIf: true}},
+		{"after if else", "package p\n\nfunc f() {\n\tif x {\n\t} else {\n\t}\n\tel|\n}", CursorContext{Kind: ContextStatement}},
+		{"var type", "package p\n\nvar x ma|", CursorContext{Kind: ContextType}},
+		{"param type", "package p\n\nfunc f(a ch|) {}", CursorContext{Kind: ContextType}},
+		{"pointer field", "package p\n\ntype T struct {\n\tx *st|\n}", CursorContext{Kind: ContextType}},
+		{"make", "package p\n\nfunc f() {\n\tx := make(ma|)\n}", CursorContext{Kind: ContextType}},
+		{"type case", "package p\n\nfunc f() {\n\tswitch x.(type) {\n\tcase in|:\n\t}\n}", CursorContext{Kind: ContextType}},
+		{"expression", "package p\n\nfunc f() {\n\tx := fu|\n}", CursorContext{Kind: ContextExpression}},
+		{"decl name", "package p\n\nvar na| = 1", CursorContext{Kind: ContextName}},
+		{"func name", "package p\n\nfunc na|() {}", CursorContext{Kind: ContextName}},
+		{"method name", "package p\n\nfunc (t T) na|() {}", CursorContext{Kind: ContextName}},
+	}
+	for _, tt := range tests {
+		offset := strings.Index(tt.code, "|")
+		code := strings.Replace(tt.code, "|", "", 1)
+		gf, err := ParseGoCode("main.go", []byte(code))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		line := strings.Count(code[:offset], "\n")
+		column := offset - strings.LastIndex(code[:offset], "\n") - 1
+		if got := gf.CursorContext(Position{Line: line, Column: column}); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
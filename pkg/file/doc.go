@@ -0,0 +1,90 @@
+package file
+
+import (
+	"go/ast"
+	"go/doc/comment"
+	"go/token"
+	"strings"
+)
+
+// DocAt 返回 pos 处声明的文档注释，pos 是包级别声明、方法、结构体字段或者接口方法的名称。
+// 没有括号的 var/const/type 声明，文档注释在 GenDecl 上
+func (g *GoFile) DocAt(pos token.Pos) string {
+	if g.File == nil {
+		return ""
+	}
+	for _, decl := range g.File.Decls {
+		if pos < decl.Pos() || pos >= decl.End() {
+			continue
+		}
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Pos() == pos {
+				return d.Doc.Text()
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Pos() == pos {
+						return specDoc(d, s.Doc, s.Comment)
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Pos() == pos {
+							return specDoc(d, s.Doc, s.Comment)
+						}
+					}
+				}
+			}
+		}
+
+		// 结构体字段和接口方法
+		var doc string
+		ast.Inspect(decl, func(n ast.Node) bool {
+			field, ok := n.(*ast.Field)
+			if !ok {
+				return n != nil && doc == ""
+			}
+			for _, name := range field.Names {
+				if name.Pos() == pos {
+					doc = specDoc(nil, field.Doc, field.Comment)
+				}
+			}
+			return doc == ""
+		})
+		return doc
+	}
+	return ""
+}
+
+// specDoc 优先使用声明上方的注释，其次是行尾注释
+func specDoc(gen *ast.GenDecl, doc, line *ast.CommentGroup) string {
+	if doc != nil {
+		return doc.Text()
+	}
+	if gen != nil && !gen.Lparen.IsValid() && gen.Doc != nil {
+		return gen.Doc.Text()
+	}
+	return line.Text()
+}
+
+// DocMarkdown 将文档注释转换为 markdown，格式规则和 go doc 相同
+func DocMarkdown(doc string) string {
+	if doc == "" {
+		return ""
+	}
+	var parser comment.Parser
+	var printer comment.Printer
+	return strings.TrimSpace(string(printer.Markdown(parser.Parse(doc))))
+}
+
+// IsDeprecated 文档注释中是否有以 Deprecated: 开头的段落
+func IsDeprecated(doc string) bool {
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		if strings.HasPrefix(strings.TrimSpace(paragraph), "Deprecated: ") {
+			return true
+		}
+	}
+	return false
+}
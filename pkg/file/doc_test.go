@@ -0,0 +1,71 @@
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+const docCode = `package p
+
+// Open opens the file.
+//
+// Deprecated: use OpenFile.
+func Open() {}
+
+// Mode is a file mode.
+type Mode int
+
+const (
+	// Read mode
+	Read Mode = 1
+	Write Mode = 2 // Write mode
+)
+
+type File struct {
+	// Name of the file
+	Name string
+	Size int // Size in bytes
+}
+
+// Close closes the file.
+func (f *File) Close() error { return nil }
+
+type Closer interface {
+	// Close closes.
+	Close() error
+}
+`
+
+func TestDocAt(t *testing.T) {
+	gf, err := ParseGoCode("p.go", []byte(docCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf := gf.FileSet.File(gf.File.Pos())
+	tests := []struct {
+		needle, want string // needle 中第一个大写字母开始是声明的名称
+	}{
+		{"func Open", "Open opens the file.\n\nDeprecated: use OpenFile.\n"},
+		{"type Mode", "Mode is a file mode.\n"},
+		{"\tRead Mode", "Read mode\n"},
+		{"\tWrite Mode", "Write mode\n"},
+		{"\tName string", "Name of the file\n"},
+		{"\tSize int", "Size in bytes\n"},
+		{") Close", "Close closes the file.\n"},
+		{"\tClose()", "Close closes.\n"},
+	}
+	for _, tt := range tests {
+		offset := strings.Index(docCode, tt.needle) + strings.IndexAny(tt.needle, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		if got := gf.DocAt(tf.Pos(offset)); got != tt.want {
+			t.Errorf("DocAt(%q) = %q, want %q", tt.needle, got, tt.want)
+		}
+	}
+
+	doc := gf.DocAt(tf.Pos(strings.Index(docCode, "Open()")))
+	if !IsDeprecated(doc) || IsDeprecated("Mode is a file mode.\n") {
+		t.Errorf("IsDeprecated(%q) wrong", doc)
+	}
+	if got := DocMarkdown(doc); got != "Open opens the file.\n\nDeprecated: use OpenFile." {
+		t.Errorf("DocMarkdown = %q", got)
+	}
+}
@@ -0,0 +1,169 @@
+package file
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// ExpectedType 推断 pos 处表达式需要的类型，比如赋值的左边、函数的返回值、调用的参数和比较的另一侧。
+// 推断不出或者没有要求时返回空字符串，其他包中的类型带有包名
+func (g *GoFile) ExpectedType(pos token.Pos, resolver FuncResolver) string {
+	if g.File == nil || !pos.IsValid() {
+		return ""
+	}
+	pos = g.skipBlanksBefore(pos)
+
+	// 包含 pos 的节点，输入到一半的调用可能没有 ) 结束位置无效
+	var path []ast.Node
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if pos < n.Pos() || n.End().IsValid() && pos > n.End() {
+			return false
+		}
+		path = append(path, n)
+		return true
+	})
+
+	for i := len(path) - 1; i >= 0; i-- {
+		switch n := path[i].(type) {
+		case *ast.AssignStmt:
+			if pos <= n.TokPos || n.Tok == token.DEFINE {
+				return ""
+			}
+			j := exprAt(n.Rhs, pos)
+			if j >= len(n.Lhs) || len(n.Rhs) > len(n.Lhs) {
+				return ""
+			}
+			return g.inferOne(n.Lhs[j], resolver, 0)
+		case *ast.ValueSpec:
+			if n.Type == nil || pos <= n.Type.End() {
+				return ""
+			}
+			return getTypeString(n.Type)
+		case *ast.ReturnStmt:
+			fn := g.EnclosingFunc(pos)
+			if fn == nil {
+				return ""
+			}
+			if j := exprAt(n.Results, pos); j < len(fn.Returns) {
+				return fn.Returns[j].Type
+			}
+			return ""
+		case *ast.CallExpr:
+			if pos <= n.Lparen || n.Rparen.IsValid() && pos > n.Rparen {
+				continue
+			}
+			return g.paramType(n, exprAt(n.Args, pos), resolver)
+		case *ast.BinaryExpr:
+			if pos <= n.OpPos {
+				continue
+			}
+			switch n.Op {
+			case token.LAND, token.LOR:
+				return "bool"
+			case token.SHL, token.SHR:
+				return ""
+			}
+			return g.inferOne(n.X, resolver, 0)
+		case *ast.SendStmt:
+			if pos <= n.Arrow {
+				return ""
+			}
+			return elemType(g.inferOne(n.Chan, resolver, 0))
+		case *ast.BlockStmt, *ast.FuncLit, *ast.CompositeLit, *ast.ExprStmt:
+			// 语句的开头和复合字面量中没有确定的类型
+			return ""
+		}
+	}
+	return ""
+}
+
+// skipBlanksBefore 光标前面是同一行的空白时，使用空白之前的位置，return 后面没有表达式时语句在空白前结束
+func (g *GoFile) skipBlanksBefore(pos token.Pos) token.Pos {
+	tf := g.FileSet.File(pos)
+	if tf == nil {
+		return pos
+	}
+	offset := tf.Offset(pos)
+	for offset > 0 && offset <= len(g.text.data) && (g.text.data[offset-1] == ' ' || g.text.data[offset-1] == '\t') {
+		offset--
+	}
+	return tf.Pos(offset)
+}
+
+// exprAt pos 所在的表达式的下标，pos 在全部表达式之后时返回 len(exprs)
+func exprAt(exprs []ast.Expr, pos token.Pos) int {
+	for i, expr := range exprs {
+		if pos <= expr.End() {
+			return i
+		}
+	}
+	return len(exprs)
+}
+
+// paramType 调用的第 i 个参数的类型，可变参数 ...T 的每个参数都是 T
+func (g *GoFile) paramType(call *ast.CallExpr, i int, resolver FuncResolver) string {
+	fn := g.callee(call.Fun, resolver)
+	if fn == nil || len(fn.Params) == 0 {
+		return ""
+	}
+	last := fn.Params[len(fn.Params)-1].Type
+	if i >= len(fn.Params)-1 && strings.HasPrefix(last, "...") {
+		if call.Ellipsis.IsValid() {
+			return "[]" + strings.TrimPrefix(last, "...")
+		}
+		return strings.TrimPrefix(last, "...")
+	}
+	if i >= len(fn.Params) {
+		return ""
+	}
+	return fn.Params[i].Type
+}
+
+// callee 被调用的函数的签名，其他包中函数的参数类型加上包名
+func (g *GoFile) callee(fun ast.Expr, resolver FuncResolver) *FuncSpec {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		if decl, _ := g.LookupVisible(f.Name, f.Pos()); decl != nil && decl.Type == DeclSpecTypeFunc {
+			if fn, ok := g.Functions["global"][f.Name]; ok {
+				return &fn
+			}
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := f.X.(*ast.Ident); ok {
+			if decl, _ := g.LookupVisible(pkg.Name, pkg.Pos()); decl != nil && decl.Type == DeclSpecTypeImport {
+				if resolver == nil {
+					return nil
+				}
+				fn, ok := resolver.LookupFunc(g.importPackageName(pkg.Name), f.Sel.Name)
+				if !ok {
+					return nil
+				}
+				qualified := *fn
+				qualified.Params = make([]TypeInfoSpec, len(fn.Params))
+				for i, param := range fn.Params {
+					param.Type = QualifyType(param.Type, pkg.Name)
+					qualified.Params[i] = param
+				}
+				return &qualified
+			}
+		}
+	}
+	typ := g.inferOne(fun, resolver, 0)
+	if !strings.HasPrefix(typ, "func") {
+		return nil
+	}
+	fn, err := ParseFuncSignature("", typ)
+	if err != nil {
+		return nil
+	}
+	return fn
+}
+
+// DeclType 推断声明的类型，函数为函数签名，推断不出时返回空字符串
+func (g *GoFile) DeclType(decl *DeclSpec, resolver FuncResolver) string {
+	return g.inferDecl(decl, resolver, 0)
+}
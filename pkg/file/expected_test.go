@@ -0,0 +1,59 @@
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpectedType(t *testing.T) {
+	const header = `package p
+
+import "strings"
+
+type User struct{ Name string }
+
+func find(id int64, names ...string) (*User, error) { return nil, nil }
+
+func f(u *User, ch chan int, id int64) (string, error) {
+	var count int
+	_ = count
+`
+	resolver := fakeFuncResolver{"strings.Repeat": "func(s string, count int) string"}
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"count = |", "int"},
+		{"count = co|", "int"},
+		{"count += |\n", "int"},
+		{"var s string = |", "string"},
+		{"return |", "string"},
+		{"return \"\", |", "error"},
+		{"return na|, nil", "string"},
+		{"find(|)", "int64"},
+		{"find(1, |)", "string"},
+		{"find(1, \"a\", |", "string"},
+		{"find(1, x|...)", "[]string"},
+		{"strings.Repeat(\"a\", |)", "int"},
+		{"if id == |", "int64"},
+		{"if u != nil && |", "bool"},
+		{"ch <- |", "int"},
+		{"u.Name = |", "string"},
+		{"x := |", ""},
+		{"fi|", ""},
+		{"_ = User{Name: |}", ""},
+	}
+	for _, tt := range tests {
+		code := header + "\t" + tt.body + "\n}\n"
+		cursor := strings.Index(code, "|")
+		code = code[:cursor] + code[cursor+1:]
+		gf, err := ParseGoCode("p.go", []byte(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos := gf.FileSet.File(gf.File.Pos()).Pos(cursor)
+		if got := gf.ExpectedType(pos, resolver); got != tt.want {
+			t.Errorf("ExpectedType(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
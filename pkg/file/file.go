@@ -2,7 +2,10 @@ package file
 
 import (
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func Exists(path string) bool {
@@ -31,3 +34,38 @@ func Open(path string) (*os.File, error) {
 func ReadAll(f *os.File) ([]byte, error) {
 	return io.ReadAll(f)
 }
+
+// URIToPath 将 file:// 开头的 lsp 文档地址转换为文件路径
+func URIToPath(uri string) string {
+	if !strings.HasPrefix(uri, "file://") {
+		return uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return strings.TrimPrefix(uri, "file://")
+	}
+	path := u.Path
+	// windows 的 file:///c:/a.go 去掉盘符前的 /
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// PathToURI 将文件路径转换为 file:// 开头的 lsp 文档地址
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if len(path) >= 2 && path[1] == ':' {
+		path = "/" + strings.ToLower(path[:1]) + path[1:]
+	}
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+// NormalizeURI 统一同一个文件的不同写法，客户端可能转义 ':'、使用大写盘符，比如 file:///C%3A/a.go 和 file:///c:/a.go
+func NormalizeURI(uri string) string {
+	if !strings.HasPrefix(uri, "file://") {
+		return uri
+	}
+	return PathToURI(URIToPath(uri))
+}
@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +69,272 @@ func main() {
 
 func TestParseGoFile2(t *testing.T) {
 }
+
+func TestParseMethods(t *testing.T) {
+	code := `package p
+
+type A struct{}
+type B struct{}
+type Cache[T any] struct{}
+
+func Close() {}
+func (a *A) Close() error { return nil }
+func (b B) Close() {}
+func (B) Open(name string) {}
+func (c *Cache[T]) Get() T { var t T; return t }
+`
+	gf, err := ParseGoCode("p.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := gf.Functions["global"]["Close"]; !ok {
+		t.Errorf("plain function Close should be in Functions")
+	}
+	if len(gf.Functions["global"]) != 1 {
+		t.Errorf("methods should not be in Functions: %v", gf.Functions["global"])
+	}
+
+	tests := []struct {
+		recv    string
+		method  string
+		pointer bool
+		typ     string
+	}{
+		{"A", "Close", true, "func() error"},
+		{"B", "Close", false, "func()"},
+		{"B", "Open", false, "func(name string)"},
+		{"Cache", "Get", true, "func() T"},
+	}
+	for _, tt := range tests {
+		fn, ok := gf.Methods[tt.recv][tt.method]
+		if !ok {
+			t.Errorf("method %s.%s not found", tt.recv, tt.method)
+			continue
+		}
+		if fn.Recv.Type != tt.recv || fn.Recv.Pointer != tt.pointer || fn.Type != tt.typ {
+			t.Errorf("%s.%s: got %+v %s", tt.recv, tt.method, *fn.Recv, fn.Type)
+		}
+	}
+
+	if got := len(gf.MethodSet("A", false)); got != 0 {
+		t.Errorf("value method set of A = %d, want 0", got)
+	}
+	if got := len(gf.MethodSet("A", true)); got != 1 {
+		t.Errorf("pointer method set of A = %d, want 1", got)
+	}
+	if got := len(gf.MethodSet("B", false)); got != 2 {
+		t.Errorf("value method set of B = %d, want 2", got)
+	}
+}
+
+func TestParseGenerics(t *testing.T) {
+	code := `package p
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+type PrefixCache[T any] struct {
+	Sep   string
+	Cache Cache[T]
+}
+
+type Pair[K comparable, V any] struct{}
+
+func Sum[N Number](xs ...N) N { var s N; return s }
+
+func (c *PrefixCache[T]) Value(key string) []T { return nil /*value*/ }
+`
+	gf, err := ParseGoCode("p.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	types := gf.Types["global"]
+	tests := []struct {
+		name string
+		decl string
+	}{
+		{"Number", "Number interface{~int | ~int64 | ~float64}"},
+		{"PrefixCache", "PrefixCache[T any] struct{Sep string; Cache Cache[T]}"},
+		{"Pair", "Pair[K comparable, V any] struct{}"},
+	}
+	for _, tt := range tests {
+		if got := types[tt.name].Decl(); got != tt.decl {
+			t.Errorf("%s.Decl() = %q, want %q", tt.name, got, tt.decl)
+		}
+	}
+
+	sum := gf.Functions["global"]["Sum"]
+	if len(sum.TypeParams) != 1 || *sum.TypeParams[0].Name != "N" || sum.TypeParams[0].Type != "Number" {
+		t.Errorf("Sum type params = %+v", sum.TypeParams)
+	}
+	if sum.Type != "func[N Number](xs ...N) N" {
+		t.Errorf("Sum type = %q", sum.Type)
+	}
+
+	// 泛型接收者的类型参数在方法中可见
+	pos := gf.FileSet.File(gf.File.Pos()).Pos(strings.Index(code, "/*value*/"))
+	decl, _ := gf.LookupVisible("T", pos)
+	if decl == nil || decl.Type != DeclSpecTypeType {
+		t.Errorf("receiver type param T should be visible in method body")
+	}
+}
+
+func TestParseImports(t *testing.T) {
+	code := `package p
+
+import (
+	"fmt"
+	"strings"
+	lg "log"
+	. "math"
+	_ "embed"
+	emb "embed"
+	"gopkg.in/yaml.v3"
+	"github.com/a/b/v2"
+	"pkg.nimblebun.works/go-lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+`
+	gf, err := ParseGoCode("p.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gf.Imports) != 10 {
+		t.Fatalf("imports = %d, want 10", len(gf.Imports))
+	}
+
+	tests := []struct {
+		path  string
+		name  string
+		alias string
+	}{
+		{"fmt", "fmt", ""},
+		{"log", "lg", "lg"},
+		{"math", ".", "."},
+		{"gopkg.in/yaml.v3", "yaml", ""},
+		{"github.com/a/b/v2", "b", ""},
+		{"pkg.nimblebun.works/go-lsp", "lsp", ""},
+	}
+	for _, tt := range tests {
+		imports := gf.ImportsOf(tt.path)
+		if len(imports) != 1 || imports[0].Name != tt.name || imports[0].Alias != tt.alias {
+			t.Errorf("import %s = %+v, want name %q alias %q", tt.path, imports, tt.name, tt.alias)
+		}
+	}
+	if !gf.ImportsOf("math")[0].IsDot() {
+		t.Errorf("dot import not recognized")
+	}
+	// 同一个路径的多个 import 都保留
+	embeds := gf.ImportsOf("embed")
+	if len(embeds) != 2 || !embeds[0].IsBlank() || embeds[1].Name != "emb" {
+		t.Errorf("embed imports = %+v, want _ and emb", embeds)
+	}
+
+	gf.ResolveImportNames(func(path string) (string, bool) {
+		if path == "github.com/sourcegraph/jsonrpc2" {
+			return "rpc", true
+		}
+		return "", false
+	})
+	if _, ok := gf.ImportByName("rpc"); !ok {
+		t.Errorf("import name should be resolved to rpc")
+	}
+	if decl, _ := gf.LookupVisible("rpc", gf.File.End()); decl == nil || decl.Type != DeclSpecTypeImport {
+		t.Errorf("scope should declare the resolved import name")
+	}
+	if decl, _ := gf.LookupVisible("jsonrpc2", gf.File.End()); decl != nil {
+		t.Errorf("assumed import name should be replaced")
+	}
+	if _, ok := gf.ImportByName("lg"); !ok {
+		t.Errorf("aliased import should keep its alias")
+	}
+}
+
+func TestParseBrokenCode(t *testing.T) {
+	code := `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+	Tags []string
+}
+
+func (u *User) Hello(prefix string) string {
+	return prefix + u.Name
+}
+
+func main() {
+	u := &User{Name: "a"}
+	for i, tag := range u.Tags {
+		fmt.Println(i, strings.ToUpper(tag))
+	}
+	switch x := any(u).(type) {
+	case *User:
+		_ = x
+	}
+	fn := func(a, b int) int { return a + b }
+	_ = fn
+}
+`
+	// 模拟输入过程中的中间状态(只输入了一部分，或者删掉了一个字符)，都不能 panic 或者丢弃整个文件
+	for i := range code {
+		for _, src := range []string{code[:i], code[:i] + code[i+1:]} {
+			gf, err := ParseGoCode("main.go", []byte(src))
+			if err != nil {
+				t.Fatalf("offset %d: %v", i, err)
+			}
+			if gf.File == nil {
+				t.Fatalf("offset %d: no ast", i)
+			}
+		}
+	}
+
+	broken := strings.Replace(code, "_ = fn\n", "fn(1, \n", 1)
+	gf, err := ParseGoCode("main.go", []byte(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gf.SyntaxErrors) == 0 {
+		t.Fatal("expected syntax errors")
+	}
+	if _, ok := gf.Types["global"]["User"]; !ok {
+		t.Error("type User lost")
+	}
+	if _, ok := gf.Methods["User"]["Hello"]; !ok {
+		t.Error("method Hello lost")
+	}
+	if _, ok := gf.Functions["global"]["main"]; !ok {
+		t.Error("func main lost")
+	}
+	if decl, _ := gf.LookupVisible("u", gf.Scope.Children[0].Scope.End-1); decl == nil {
+		t.Error("local u lost")
+	}
+}
+
+func TestNormalizeURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"file:///work/a.go", "file:///work/a.go"},
+		{"file:///work/my%20dir/a.go", "file:///work/my%20dir/a.go"},
+		{"file:///c%3A/work/a.go", "file:///c:/work/a.go"},
+		{"file:///C:/work/a.go", "file:///c:/work/a.go"},
+		{"untitled:Untitled-1", "untitled:Untitled-1"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeURI(tt.uri); got != tt.want {
+			t.Errorf("NormalizeURI(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+	if got := NormalizeURI(PathToURI("/work/a.go")); got != PathToURI("/work/a.go") {
+		t.Errorf("PathToURI is not normalized: %q", got)
+	}
+}
@@ -0,0 +1,182 @@
+package file
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TextEdit 用行列表示的文本修改，行列从 0 开始，列按字节计算，Start 和 End 相同时为插入
+type TextEdit struct {
+	Start   Position
+	End     Position
+	NewText string
+}
+
+// IsStdImport 按照 goimports 的规则判断是否为标准库，路径的第一段没有 . 时是标准库
+func IsStdImport(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// ImportPathAt pos 在 import 声明的路径字符串中时，返回引号和 pos 之间已经输入的路径，以及路径开始的位置。
+// 输入到一半没有结束引号的路径也会被解析为 import
+func (g *GoFile) ImportPathAt(pos token.Pos) (string, Position, bool) {
+	if g.File == nil {
+		return "", Position{}, false
+	}
+	for _, spec := range g.File.Imports {
+		lit := spec.Path
+		if lit == nil || lit.Value == "" || pos <= lit.Pos() || pos > lit.End() {
+			continue
+		}
+		// 结束引号之后不在字符串中
+		if len(lit.Value) > 1 && lit.Value[len(lit.Value)-1] == lit.Value[0] && pos == lit.End() {
+			return "", Position{}, false
+		}
+		return lit.Value[1 : pos-lit.Pos()], g.position(lit.Pos() + 1), true
+	}
+	return "", Position{}, false
+}
+
+// ImportEdit 返回添加 import path 需要的修改，已经导入时返回 false。
+// 和 goimports 一样标准库和其他包分组，插入到同类分组中按路径排序的位置，没有同类分组时新建一组
+func (g *GoFile) ImportEdit(path string) (TextEdit, bool) {
+	if len(g.ImportsOf(path)) > 0 || g.File == nil || g.File.Name == nil || !g.File.Name.Pos().IsValid() {
+		return TextEdit{}, false
+	}
+
+	var decls []*ast.GenDecl
+	for _, decl := range g.File.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			decls = append(decls, gen)
+		}
+	}
+	if len(decls) == 0 {
+		end := g.position(g.File.Name.End())
+		return TextEdit{Start: end, End: end, NewText: "\n\nimport " + strconv.Quote(path)}, true
+	}
+	for _, decl := range decls {
+		if decl.Lparen.IsValid() && len(decl.Specs) > 0 {
+			if edit, ok := g.groupImportEdit(decl, path); ok {
+				return edit, true
+			}
+			return g.rebuildImportDecl(decl, path), true
+		}
+	}
+	if len(decls) == 1 {
+		return g.rebuildImportDecl(decls[0], path), true
+	}
+	// 多个单独的 import 声明，添加到最后一个后面
+	end := g.position(decls[len(decls)-1].End())
+	return TextEdit{Start: end, End: end, NewText: "\nimport " + strconv.Quote(path)}, true
+}
+
+// groupImportEdit 在带括号的 import 声明中按行插入，import 和括号写在同一行时返回 false
+func (g *GoFile) groupImportEdit(decl *ast.GenDecl, path string) (TextEdit, bool) {
+	line := func(pos token.Pos) int { return g.position(pos).Line }
+	specs := make([]*ast.ImportSpec, 0, len(decl.Specs))
+	for _, spec := range decl.Specs {
+		specs = append(specs, spec.(*ast.ImportSpec))
+	}
+	if line(specs[0].Pos()) == line(decl.Lparen) || line(specs[len(specs)-1].End()) == line(decl.Rparen) {
+		return TextEdit{}, false
+	}
+
+	// 空行分隔的分组
+	var groups [][]*ast.ImportSpec
+	for i, spec := range specs {
+		if i == 0 || line(spec.Pos())-line(specs[i-1].End()) > 1 {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], spec)
+	}
+
+	std := IsStdImport(path)
+	indent := g.indent(specs[0].Pos())
+	quoted := strconv.Quote(path)
+	insertAt := func(line int, text string) TextEdit {
+		pos := Position{Line: line}
+		return TextEdit{Start: pos, End: pos, NewText: text}
+	}
+	for _, group := range groups {
+		same := false
+		for _, spec := range group {
+			same = same || IsStdImport(importPath(spec)) == std
+		}
+		if !same {
+			continue
+		}
+		for _, spec := range group {
+			if importPath(spec) > path {
+				return insertAt(line(spec.Pos()), g.indent(spec.Pos())+quoted+"\n"), true
+			}
+		}
+		last := group[len(group)-1]
+		return insertAt(line(last.End())+1, g.indent(last.Pos())+quoted+"\n"), true
+	}
+	if std {
+		return insertAt(line(specs[0].Pos()), indent+quoted+"\n\n"), true
+	}
+	return insertAt(line(specs[len(specs)-1].End())+1, "\n"+indent+quoted+"\n"), true
+}
+
+// rebuildImportDecl 把整个 import 声明替换为带括号的分组形式
+func (g *GoFile) rebuildImportDecl(decl *ast.GenDecl, path string) TextEdit {
+	type entry struct{ path, text string }
+	entries := []entry{{path: path, text: strconv.Quote(path)}}
+	tf := g.FileSet.File(decl.Pos())
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ImportSpec)
+		text := string(g.text.data[tf.Offset(spec.Pos()):tf.Offset(spec.End())])
+		entries = append(entries, entry{path: importPath(spec), text: text})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		si, sj := IsStdImport(entries[i].path), IsStdImport(entries[j].path)
+		if si != sj {
+			return si
+		}
+		return entries[i].path < entries[j].path
+	})
+
+	var sb strings.Builder
+	sb.WriteString("import (\n")
+	for i, e := range entries {
+		if i > 0 && IsStdImport(entries[i-1].path) != IsStdImport(e.path) {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString("\t" + e.text + "\n")
+	}
+	sb.WriteString(")")
+	return TextEdit{Start: g.position(decl.Pos()), End: g.position(decl.End()), NewText: sb.String()}
+}
+
+// indent pos 所在行 pos 之前的空白
+func (g *GoFile) indent(pos token.Pos) string {
+	p := g.position(pos)
+	start, _, ok := g.text.lineRange(p.Line)
+	if !ok {
+		return "\t"
+	}
+	prefix := g.text.data[start : start+p.Column]
+	if strings.TrimLeft(string(prefix), " \t") != "" {
+		return "\t"
+	}
+	return string(prefix)
+}
+
+// position 将 token.Pos 转换为从 0 开始的行列
+func (g *GoFile) position(pos token.Pos) Position {
+	p := g.FileSet.Position(pos)
+	return Position{Filename: p.Filename, Line: p.Line - 1, Column: p.Column - 1}
+}
+
+func importPath(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return spec.Path.Value
+	}
+	return path
+}
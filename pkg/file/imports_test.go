@@ -0,0 +1,156 @@
+package file
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// applyEdit 按行列把修改应用到源码上
+func applyEdit(code string, edit TextEdit) string {
+	t := newText([]byte(code))
+	offset := func(p Position) int {
+		start, _, _ := t.lineRange(p.Line)
+		if p.Line >= len(t.lines) {
+			return len(code)
+		}
+		return start + p.Column
+	}
+	return code[:offset(edit.Start)] + edit.NewText + code[offset(edit.End):]
+}
+
+func TestImportEdit(t *testing.T) {
+	tests := []struct {
+		name, code, path, want string
+	}{
+		{
+			"no imports",
+			"package p\n\nfunc f() {}\n",
+			"strings",
+			"package p\n\nimport \"strings\"\n\nfunc f() {}\n",
+		},
+		{
+			"single import",
+			"package p\n\nimport \"os\"\n",
+			"github.com/a/b",
+			"package p\n\nimport (\n\t\"os\"\n\n\t\"github.com/a/b\"\n)\n",
+		},
+		{
+			"sorted in std group",
+			"package p\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\n\t\"github.com/a/b\"\n)\n",
+			"io",
+			"package p\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"os\"\n\n\t\"github.com/a/b\"\n)\n",
+		},
+		{
+			"end of third party group",
+			"package p\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/a/b\"\n)\n",
+			"golang.org/x/mod",
+			"package p\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/a/b\"\n\t\"golang.org/x/mod\"\n)\n",
+		},
+		{
+			"new std group",
+			"package p\n\nimport (\n\t\"github.com/a/b\"\n)\n",
+			"fmt",
+			"package p\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/a/b\"\n)\n",
+		},
+		{
+			"new third party group",
+			"package p\n\nimport (\n\t\"fmt\"\n)\n",
+			"github.com/a/b",
+			"package p\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/a/b\"\n)\n",
+		},
+		{
+			"mixed group",
+			"package p\n\nimport (\n\t\"fmt\"\n\t\"github.com/a/b\"\n\tlsp \"pkg.x/go-lsp\"\n\t\"strings\"\n)\n",
+			"os",
+			"package p\n\nimport (\n\t\"fmt\"\n\t\"github.com/a/b\"\n\t\"os\"\n\tlsp \"pkg.x/go-lsp\"\n\t\"strings\"\n)\n",
+		},
+		{
+			"one line group",
+			"package p\n\nimport (\"os\"; \"fmt\")\n",
+			"io",
+			"package p\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"os\"\n)\n",
+		},
+	}
+	for _, tt := range tests {
+		gf, err := ParseGoCode("main.go", []byte(tt.code))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		edit, ok := gf.ImportEdit(tt.path)
+		if !ok {
+			t.Errorf("%s: no edit", tt.name)
+			continue
+		}
+		if got := applyEdit(tt.code, edit); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+
+	gf, _ := ParseGoCode("main.go", []byte("package p\n\nimport \"os\"\n"))
+	if _, ok := gf.ImportEdit("os"); ok {
+		t.Errorf("os is already imported")
+	}
+}
+
+func TestStdPackages(t *testing.T) {
+	std := StdPackages()
+	for name, path := range map[string]string{"strings": "strings", "json": "encoding/json", "rand": "math/rand/v2"} {
+		found := false
+		for _, p := range std[name] {
+			found = found || p == path
+		}
+		if !found {
+			t.Errorf("std package %s: %v, want %s", name, std[name], path)
+		}
+	}
+	for _, paths := range std {
+		for _, path := range paths {
+			if strings.Contains(path, "internal") || strings.HasPrefix(path, "cmd/") || path == "builtin" {
+				t.Errorf("%s should not be listed", path)
+			}
+		}
+	}
+}
+
+func TestStdVersion(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	if v := StdVersion(); !strings.HasPrefix(v, "go") {
+		t.Errorf("StdVersion() = %q", v)
+	}
+}
+
+func TestImportPathAt(t *testing.T) {
+	tests := []struct {
+		code   string
+		prefix string
+		ok     bool
+	}{
+		{"package p\n\nimport (\n\t\"fmt\"\n\t\"github.com/den|\n)\n", "github.com/den", true},
+		{"package p\n\nimport \"|\"\n", "", true},
+		{"package p\n\nimport \"net/|http\"\n", "net/", true},
+		{"package p\n\nimport x \"str|\"\n", "str", true},
+		{"package p\n\nimport \"fmt\"|\n", "", false},
+		{"package p\n\nimport |\"fmt\"\n", "", false},
+		{"package p\n\nfunc f() { _ = \"fm|\" }\n", "", false},
+	}
+	for _, tt := range tests {
+		cursor := strings.Index(tt.code, "|")
+		code := tt.code[:cursor] + tt.code[cursor+1:]
+		gf, err := ParseGoCode("p.go", []byte(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix, start, ok := gf.ImportPathAt(gf.FileSet.File(gf.File.Pos()).Pos(cursor))
+		if ok != tt.ok || prefix != tt.prefix {
+			t.Errorf("ImportPathAt(%q) = %q, %v, want %q, %v", tt.code, prefix, ok, tt.prefix, tt.ok)
+			continue
+		}
+		// 路径开始的位置加上已经输入的长度就是光标
+		if ok && gf.TokenPos(Position{Line: start.Line, Column: start.Column + len(prefix)}) != gf.FileSet.File(gf.File.Pos()).Pos(cursor) {
+			t.Errorf("ImportPathAt(%q) start = %+v", tt.code, start)
+		}
+	}
+}
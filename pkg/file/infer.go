@@ -0,0 +1,482 @@
+package file
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// maxInferDepth 推断时追踪变量声明的最大深度，防止循环引用
+const maxInferDepth = 16
+
+// FuncResolver 查找其他包中的函数，pkg 为包名，用于推断调用表达式的类型
+type FuncResolver interface {
+	LookupFunc(pkg, name string) (*FuncSpec, bool)
+}
+
+// builtinTypes go 的预声明类型，T(x) 是类型转换
+var builtinTypes = map[string]struct{}{
+	"bool": {}, "byte": {}, "rune": {}, "string": {}, "error": {}, "any": {},
+	"int": {}, "int8": {}, "int16": {}, "int32": {}, "int64": {},
+	"uint": {}, "uint8": {}, "uint16": {}, "uint32": {}, "uint64": {}, "uintptr": {},
+	"float32": {}, "float64": {}, "complex64": {}, "complex128": {},
+}
+
+// InferType 推断表达式的类型，多返回值的调用返回多个类型，推断不出的类型为空字符串。
+// 只做语法层面的推断，不加载 go/types，resolver 为 nil 时不查找其他包
+func (g *GoFile) InferType(expr ast.Expr, resolver FuncResolver) []string {
+	return g.inferExpr(expr, resolver, 0)
+}
+
+// InferIdentType 推断 pos 处可见的标识符 name 的类型
+func (g *GoFile) InferIdentType(name string, pos token.Pos, resolver FuncResolver) string {
+	decl, _ := g.LookupVisible(name, pos)
+	if decl == nil {
+		return ""
+	}
+	return g.inferDecl(decl, resolver, 0)
+}
+
+// ParseFuncSignature 解析函数签名，比如索引中保存的 func[T any](a T) (int, error)
+func ParseFuncSignature(name, sig string) (*FuncSpec, error) {
+	// 函数类型字面量不能有类型参数，解析前去掉
+	src := sig
+	if strings.HasPrefix(src, "func[") {
+		if end := matchingBracket(src, len("func")); end > 0 {
+			src = "func" + src[end+1:]
+		}
+	}
+	ft, ok := parseTypeExpr(src).(*ast.FuncType)
+	if !ok {
+		return nil, fmt.Errorf("invalid func signature: %s", sig)
+	}
+	return &FuncSpec{
+		Name:    name,
+		Type:    sig,
+		Params:  parseFieldList(ft.Params),
+		Returns: parseFieldList(ft.Results),
+	}, nil
+}
+
+// matchingBracket 返回 s[open] 处的 [ 对应的 ] 的位置
+func matchingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseTypeExpr 将类型字符串解析成 ast，用于取元素类型、解引用等操作
+func parseTypeExpr(typ string) ast.Expr {
+	if typ == "" {
+		return nil
+	}
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return nil
+	}
+	return expr
+}
+
+// elemType 返回 []T [N]T *[N]T map[K]V chan T 的元素类型，string 的元素类型为 byte
+func elemType(typ string) string {
+	if typ == "string" {
+		return "byte"
+	}
+	switch t := parseTypeExpr(typ).(type) {
+	case *ast.ArrayType:
+		return getTypeString(t.Elt)
+	case *ast.MapType:
+		return getTypeString(t.Value)
+	case *ast.ChanType:
+		return getTypeString(t.Value)
+	case *ast.StarExpr:
+		if arr, ok := t.X.(*ast.ArrayType); ok {
+			return getTypeString(arr.Elt)
+		}
+	}
+	return ""
+}
+
+// derefType 去掉指针，*T -> T
+func derefType(typ string) string {
+	return strings.TrimPrefix(typ, "*")
+}
+
+// RangeTypes 返回 range 子句的 key 和 value 的类型
+func RangeTypes(typ string) (string, string) {
+	switch typ {
+	case "":
+		return "", ""
+	case "string":
+		return "int", "rune"
+	}
+	if _, ok := builtinTypes[typ]; ok {
+		// range 整数
+		return typ, ""
+	}
+	switch t := parseTypeExpr(typ).(type) {
+	case *ast.ArrayType:
+		return "int", getTypeString(t.Elt)
+	case *ast.StarExpr:
+		if arr, ok := t.X.(*ast.ArrayType); ok {
+			return "int", getTypeString(arr.Elt)
+		}
+	case *ast.MapType:
+		return getTypeString(t.Key), getTypeString(t.Value)
+	case *ast.ChanType:
+		return getTypeString(t.Value), ""
+	case *ast.FuncType:
+		// 迭代器 func(yield func(K, V) bool)
+		if t.Params == nil || len(t.Params.List) != 1 {
+			break
+		}
+		yield, ok := t.Params.List[0].Type.(*ast.FuncType)
+		if !ok {
+			break
+		}
+		params := parseFieldList(yield.Params)
+		var k, v string
+		if len(params) > 0 {
+			k = params[0].Type
+		}
+		if len(params) > 1 {
+			v = params[1].Type
+		}
+		return k, v
+	}
+	return "", ""
+}
+
+// resultTypes 返回函数类型字符串的返回值类型
+func resultTypes(typ string) []string {
+	fn, err := ParseFuncSignature("", typ)
+	if err != nil {
+		return nil
+	}
+	return specTypes(fn.Returns)
+}
+
+func specTypes(specs []TypeInfoSpec) []string {
+	types := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		types = append(types, spec.Type)
+	}
+	return types
+}
+
+func first(types []string) string {
+	if len(types) == 0 {
+		return ""
+	}
+	return types[0]
+}
+
+func (g *GoFile) inferOne(expr ast.Expr, r FuncResolver, depth int) string {
+	return first(g.inferExpr(expr, r, depth))
+}
+
+func (g *GoFile) inferExpr(expr ast.Expr, r FuncResolver, depth int) []string {
+	if expr == nil || depth > maxInferDepth {
+		return nil
+	}
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return []string{"int"}
+		case token.FLOAT:
+			return []string{"float64"}
+		case token.IMAG:
+			return []string{"complex128"}
+		case token.CHAR:
+			return []string{"rune"}
+		case token.STRING:
+			return []string{"string"}
+		}
+	case *ast.CompositeLit:
+		return []string{getTypeString(e.Type)}
+	case *ast.FuncLit:
+		return []string{getTypeString(e.Type)}
+	case *ast.ParenExpr:
+		return g.inferExpr(e.X, r, depth)
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.AND:
+			if t := g.inferOne(e.X, r, depth); t != "" {
+				return []string{"*" + t}
+			}
+		case token.NOT:
+			return []string{"bool"}
+		case token.ARROW:
+			return []string{elemType(g.inferOne(e.X, r, depth)), "bool"}
+		default:
+			return []string{g.inferOne(e.X, r, depth)}
+		}
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ, token.LAND, token.LOR:
+			return []string{"bool"}
+		case token.SHL, token.SHR:
+			return []string{g.inferOne(e.X, r, depth)}
+		}
+		// 常量没有确定的类型，优先使用另一侧的类型
+		if _, ok := e.X.(*ast.BasicLit); ok {
+			return []string{g.inferOne(e.Y, r, depth)}
+		}
+		return []string{g.inferOne(e.X, r, depth)}
+	case *ast.StarExpr:
+		return []string{derefType(g.inferOne(e.X, r, depth))}
+	case *ast.TypeAssertExpr:
+		return []string{getTypeString(e.Type), "bool"}
+	case *ast.IndexExpr:
+		return []string{elemType(g.inferOne(e.X, r, depth)), "bool"}
+	case *ast.SliceExpr:
+		t := g.inferOne(e.X, r, depth)
+		if arr, ok := parseTypeExpr(derefType(t)).(*ast.ArrayType); ok && arr.Len != nil {
+			return []string{"[]" + getTypeString(arr.Elt)}
+		}
+		return []string{t}
+	case *ast.SelectorExpr:
+		return []string{g.inferSelector(e, r, depth)}
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			return []string{"bool"}
+		case "iota":
+			return []string{"int"}
+		case "nil":
+			return nil
+		}
+		decl, _ := g.LookupVisible(e.Name, e.Pos())
+		if decl == nil {
+			return nil
+		}
+		return []string{g.inferDecl(decl, r, depth+1)}
+	case *ast.CallExpr:
+		return g.inferCall(e, r, depth)
+	}
+	return nil
+}
+
+// inferSelector 推断 x.f 的类型，x 为当前文件中的 struct 类型
+func (g *GoFile) inferSelector(e *ast.SelectorExpr, r FuncResolver, depth int) string {
+	if ident, ok := e.X.(*ast.Ident); ok {
+		if decl, _ := g.LookupVisible(ident.Name, ident.Pos()); decl != nil && decl.Type == DeclSpecTypeImport {
+			return ""
+		}
+	}
+	typ := derefType(g.inferOne(e.X, r, depth))
+	if t, ok := g.Types["global"][typ]; ok {
+		for _, field := range t.Fields {
+			if *field.Name == e.Sel.Name {
+				return field.Type
+			}
+		}
+	}
+	if method, ok := g.Methods[typ][e.Sel.Name]; ok {
+		return method.Type
+	}
+	return ""
+}
+
+// inferBuiltin 推断内置函数调用的类型，name 不是内置函数时返回 false
+func (g *GoFile) inferBuiltin(name string, e *ast.CallExpr, r FuncResolver, depth int) ([]string, bool) {
+	switch name {
+	case "len", "cap", "copy":
+		return []string{"int"}, true
+	case "complex":
+		return []string{"complex128"}, true
+	case "real", "imag":
+		return []string{"float64"}, true
+	case "recover":
+		return []string{"any"}, true
+	}
+	if len(e.Args) == 0 {
+		return nil, false
+	}
+	switch name {
+	case "new":
+		return []string{"*" + getTypeString(e.Args[0])}, true
+	case "make":
+		return []string{getTypeString(e.Args[0])}, true
+	case "append", "min", "max":
+		return []string{g.inferOne(e.Args[0], r, depth)}, true
+	}
+	return nil, false
+}
+
+// isTypeExpr 判断表达式是否是类型，用于识别类型转换 T(x)
+func (g *GoFile) isTypeExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType, *ast.StructType:
+		return true
+	case *ast.ParenExpr:
+		return g.isTypeExpr(e.X)
+	case *ast.StarExpr:
+		return g.isTypeExpr(e.X)
+	case *ast.IndexExpr:
+		return g.isTypeExpr(e.X)
+	case *ast.IndexListExpr:
+		return g.isTypeExpr(e.X)
+	case *ast.Ident:
+		decl, _ := g.LookupVisible(e.Name, e.Pos())
+		if decl != nil {
+			return decl.Type == DeclSpecTypeType
+		}
+		_, ok := builtinTypes[e.Name]
+		return ok
+	}
+	return false
+}
+
+func (g *GoFile) inferCall(e *ast.CallExpr, r FuncResolver, depth int) []string {
+	fun := e.Fun
+	for {
+		paren, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = paren.X
+	}
+
+	if g.isTypeExpr(fun) {
+		return []string{getTypeString(fun)}
+	}
+
+	// 泛型函数的实例化 f[int](x)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+
+	switch f := fun.(type) {
+	case *ast.Ident:
+		decl, _ := g.LookupVisible(f.Name, f.Pos())
+		if decl == nil {
+			types, _ := g.inferBuiltin(f.Name, e, r, depth)
+			return types
+		}
+		if decl.Type == DeclSpecTypeFunc {
+			if fn, ok := g.Functions["global"][f.Name]; ok {
+				return specTypes(fn.Returns)
+			}
+		}
+		// 函数类型的变量
+		return resultTypes(g.inferDecl(decl, r, depth+1))
+	case *ast.SelectorExpr:
+		if pkg, ok := f.X.(*ast.Ident); ok {
+			if decl, _ := g.LookupVisible(pkg.Name, pkg.Pos()); decl != nil && decl.Type == DeclSpecTypeImport {
+				if r == nil {
+					return nil
+				}
+				fn, ok := r.LookupFunc(g.importPackageName(pkg.Name), f.Sel.Name)
+				if !ok {
+					return nil
+				}
+				return specTypes(fn.Returns)
+			}
+		}
+		return resultTypes(g.inferSelector(f, r, depth))
+	}
+	return resultTypes(g.inferOne(fun, r, depth))
+}
+
+// importPackageName 返回 import 名称对应的真实包名，别名不是包名
+func (g *GoFile) importPackageName(name string) string {
+	n, ok := g.ImportByName(name)
+	if !ok {
+		return name
+	}
+	return n.PackageName()
+}
+
+// inferDecl 根据声明推断标识符的类型
+func (g *GoFile) inferDecl(decl *DeclSpec, r FuncResolver, depth int) string {
+	if depth > maxInferDepth {
+		return ""
+	}
+	switch n := decl.Node.(type) {
+	case *ast.ValueSpec:
+		if n.Type != nil {
+			return getTypeString(n.Type)
+		}
+		return g.inferAssign(identIndex(n.Names, decl.Name), len(n.Names), n.Values, r, depth)
+	case *ast.AssignStmt:
+		return g.inferAssign(exprIndex(n.Lhs, decl.Name), len(n.Lhs), n.Rhs, r, depth)
+	case *ast.Field:
+		// 函数内的可变参数 ...T 是 []T
+		if ellipsis, ok := n.Type.(*ast.Ellipsis); ok {
+			return "[]" + getTypeString(ellipsis.Elt)
+		}
+		return getTypeString(n.Type)
+	case *ast.RangeStmt:
+		k, v := RangeTypes(g.inferOne(n.X, r, depth+1))
+		if ident, ok := n.Key.(*ast.Ident); ok && ident.Name == decl.Name {
+			return k
+		}
+		return v
+	case *ast.CaseClause:
+		// switch v := x.(type) 只有一个类型的 case 中 v 是该类型
+		if len(n.List) == 1 {
+			if ident, ok := n.List[0].(*ast.Ident); !ok || ident.Name != "nil" {
+				return getTypeString(n.List[0])
+			}
+		}
+	case *ast.FuncDecl:
+		return getTypeString(n.Type)
+	case *ast.TypeSpec:
+		return n.Name.Name
+	}
+	return ""
+}
+
+// inferAssign 推断 a, b := x, y 或者 a, err := f() 中第 i 个变量的类型
+func (g *GoFile) inferAssign(i, lhs int, rhs []ast.Expr, r FuncResolver, depth int) string {
+	if i < 0 {
+		return ""
+	}
+	if len(rhs) == lhs {
+		return g.inferOne(rhs[i], r, depth+1)
+	}
+	if len(rhs) != 1 {
+		return ""
+	}
+	// 多返回值或者 v, ok := m[k]
+	types := g.inferExpr(rhs[0], r, depth+1)
+	if i < len(types) {
+		return types[i]
+	}
+	return ""
+}
+
+func identIndex(idents []*ast.Ident, name string) int {
+	for i, ident := range idents {
+		if ident.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func exprIndex(exprs []ast.Expr, name string) int {
+	for i, expr := range exprs {
+		if ident, ok := expr.(*ast.Ident); ok && ident.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,134 @@
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+const inferCode = `package p
+
+import "strconv"
+
+type Index struct {
+	Name string
+	Line int
+}
+
+func (i *Index) Close() error { return nil }
+
+func load(path string) (*Index, error) { return nil, nil }
+
+func names() []string { return nil }
+
+func main() {
+	a := Index{}
+	b := &Index{}
+	c, err := load("x")
+	d := a.Name
+	e := b.Close()
+	f := []int{1, 2}
+	for i, v := range f {
+		_, _ = i, v
+	}
+	m := map[string]*Index{}
+	for k, idx := range m {
+		_, _ = k, idx
+	}
+	var x any = 1
+	g := x.(string)
+	h, ok := x.(int)
+	n := int64(len(f))
+	s := []byte("abc")
+	p := strconv.Itoa(n)
+	q := names()[0]
+	r, found := m["x"]
+	t := f[1:]
+	u := 1 + 2.5
+	fn := func() (int, error) { return 0, nil }
+	w, werr := fn()
+	y := make(chan *Index)
+	z := <-y
+	_, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _ = a, b, c, err, d, e, g, h, ok, n, s, p, q, r, found, t, u, w, werr, y, z, fn, x /*end*/
+}
+`
+
+type fakeFuncResolver map[string]string
+
+func (r fakeFuncResolver) LookupFunc(pkg, name string) (*FuncSpec, bool) {
+	sig, ok := r[pkg+"."+name]
+	if !ok {
+		return nil, false
+	}
+	fn, err := ParseFuncSignature(name, sig)
+	return fn, err == nil
+}
+
+func TestInferIdentType(t *testing.T) {
+	gf, err := ParseGoCode("p.go", []byte(inferCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := gf.FileSet.File(gf.File.Pos()).Pos(strings.Index(inferCode, "/*end*/"))
+	resolver := fakeFuncResolver{"strconv.Itoa": "func(i int) string"}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a", "Index"},
+		{"b", "*Index"},
+		{"c", "*Index"},
+		{"err", "error"},
+		{"d", "string"},
+		{"e", "error"},
+		{"f", "[]int"},
+		{"g", "string"},
+		{"h", "int"},
+		{"ok", "bool"},
+		{"n", "int64"},
+		{"s", "[]byte"},
+		{"p", "string"},
+		{"q", "string"},
+		{"r", "*Index"},
+		{"found", "bool"},
+		{"t", "[]int"},
+		{"u", "float64"},
+		{"fn", "func() (int, error)"},
+		{"w", "int"},
+		{"werr", "error"},
+		{"y", "chan *Index"},
+		{"z", "*Index"},
+		{"x", "any"},
+	}
+	for _, tt := range tests {
+		if got := gf.InferIdentType(tt.name, pos, resolver); got != tt.want {
+			t.Errorf("InferIdentType(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInferRange(t *testing.T) {
+	gf, err := ParseGoCode("p.go", []byte(inferCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 解析时推断的类型保存在 Variables 中
+	tests := []struct {
+		block string
+		name  string
+		want  string
+	}{
+		{"global/main/block", "i", "int"},
+		{"global/main/block", "v", "int"},
+		{"global/main/block", "k", "string"},
+		{"global/main/block", "idx", "*Index"},
+		{"global/main/block", "c", "*Index"},
+		{"global/main/block", "err", "error"},
+	}
+	for _, tt := range tests {
+		if got := gf.Variables[tt.block][tt.name].Type; got != tt.want {
+			t.Errorf("Variables[%s][%s].Type = %q, want %q", tt.block, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package file
+
+import (
+	"sort"
+)
+
+// maxEmbedDepth 解析嵌入类型的最大深度，防止循环嵌入
+const maxEmbedDepth = 8
+
+// GoPackage 同一个包中的全部文件，用于跨文件解析类型
+type GoPackage struct {
+	Name  string
+	Files []*GoFile
+}
+
+// TypeResolver 解析其他包中的类型，pkg 为包名，返回类型以及该类型声明的方法
+type TypeResolver interface {
+	LookupType(pkg, name string) (*TypeSpec, []FuncSpec, bool)
+}
+
+// MemberSpec 类型的字段或方法
+type MemberSpec struct {
+	Field  *TypeInfoSpec // 字段和方法只有一个不为 nil
+	Method *FuncSpec
+	Depth  int    // 0 为类型自身的成员，大于 0 为从嵌入类型提升的成员
+	From   string // 提升自哪个嵌入类型，比如 *ast.File
+}
+
+func (m MemberSpec) Name() string {
+	if m.Field != nil {
+		return *m.Field.Name
+	}
+	return m.Method.Name
+}
+
+// LookupType 在包中的所有文件里查找包级别的类型
+func (p *GoPackage) LookupType(name string) (*TypeSpec, bool) {
+	for _, gf := range p.Files {
+		if t, ok := gf.Types["global"][name]; ok {
+			return &t, true
+		}
+	}
+	return nil, false
+}
+
+// Methods 返回包中所有文件里接收者为 typeName 的方法
+func (p *GoPackage) Methods(typeName string) []FuncSpec {
+	var methods []FuncSpec
+	for _, gf := range p.Files {
+		for _, method := range gf.Methods[typeName] {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// embedLevel 某一层嵌入中待展开的类型
+type embedLevel struct {
+	pkg     string // 当前包为空
+	name    string
+	pointer bool
+	from    string
+}
+
+// Members 返回类型的字段和方法，包括嵌入类型提升的成员。
+// 浅层的成员遮蔽深层的同名成员，同一层的同名成员有歧义，按 go 的规则都不可访问。
+// pointer 为 false 时不包括指针接收者的方法，resolver 为 nil 时不解析其他包的嵌入类型。
+func (p *GoPackage) Members(typeName string, pointer bool, resolver TypeResolver) []MemberSpec {
+	return p.MembersOf("", typeName, pointer, resolver)
+}
+
+// MembersOf 和 Members 相同，类型在包名为 pkg 的包中，pkg 为空时表示当前包
+func (p *GoPackage) MembersOf(pkg, typeName string, pointer bool, resolver TypeResolver) []MemberSpec {
+	var members []MemberSpec
+	seen := make(map[string]struct{})    // 已经出现过的成员名称
+	visited := make(map[string]struct{}) // 已经展开过的类型
+
+	level := []embedLevel{{pkg: pkg, name: typeName, pointer: pointer}}
+	for depth := 0; depth < maxEmbedDepth && len(level) > 0; depth++ {
+		var next []embedLevel
+		found := make(map[string][]MemberSpec)
+
+		for _, e := range level {
+			key := e.pkg + "." + e.name
+			if _, ok := visited[key]; ok {
+				continue
+			}
+			visited[key] = struct{}{}
+
+			t, methods, ok := p.resolve(e.pkg, e.name, resolver)
+			if !ok {
+				continue
+			}
+			for i := range t.Fields {
+				found[*t.Fields[i].Name] = append(found[*t.Fields[i].Name], MemberSpec{Field: &t.Fields[i], Depth: depth, From: e.from})
+			}
+			for i := range t.Methods {
+				found[t.Methods[i].Name] = append(found[t.Methods[i].Name], MemberSpec{Method: &t.Methods[i], Depth: depth, From: e.from})
+			}
+			for i := range methods {
+				if methods[i].Recv != nil && methods[i].Recv.Pointer && !e.pointer {
+					continue
+				}
+				found[methods[i].Name] = append(found[methods[i].Name], MemberSpec{Method: &methods[i], Depth: depth, From: e.from})
+			}
+
+			for _, embed := range t.Embeds {
+				pkg := embed.Package
+				// 其他包中的类型嵌入的同包类型，仍然属于其他包
+				if pkg == "" {
+					pkg = e.pkg
+				}
+				next = append(next, embedLevel{
+					pkg:     pkg,
+					name:    embed.Name,
+					pointer: e.pointer || embed.Pointer,
+					from:    embed.Type,
+				})
+			}
+		}
+
+		for name, specs := range found {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			if len(specs) > 1 {
+				continue
+			}
+			members = append(members, specs[0])
+		}
+		level = next
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Depth != members[j].Depth {
+			return members[i].Depth < members[j].Depth
+		}
+		return members[i].Name() < members[j].Name()
+	})
+	return members
+}
+
+func (p *GoPackage) resolve(pkg, name string, resolver TypeResolver) (*TypeSpec, []FuncSpec, bool) {
+	if pkg == "" || pkg == p.Name {
+		t, ok := p.LookupType(name)
+		if !ok {
+			return nil, nil, false
+		}
+		return t, p.Methods(name), true
+	}
+	if resolver == nil {
+		return nil, nil, false
+	}
+	return resolver.LookupType(pkg, name)
+}
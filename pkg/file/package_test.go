@@ -0,0 +1,153 @@
+package file
+
+import (
+	"testing"
+)
+
+const packageCodeA = `package p
+
+import "go/ast"
+
+type GoFile struct {
+	*ast.File
+	Base
+	Name string
+}
+
+type Base struct {
+	ID   int
+	Name string
+}
+
+func (b *Base) Close() error { return nil }
+`
+
+const packageCodeB = `package p
+
+import "io"
+
+type Left struct{ X int }
+type Right struct{ X int }
+
+type Both struct {
+	Left
+	Right
+}
+
+func (b Base) Open() {}
+
+type ReadCloser interface {
+	io.Reader
+	Closer
+	Flush() error
+}
+
+type Closer interface {
+	Close() error
+}
+`
+
+type fakeResolver map[string]TypeSpec
+
+func (r fakeResolver) LookupType(pkg, name string) (*TypeSpec, []FuncSpec, bool) {
+	t, ok := r[pkg+"."+name]
+	if !ok {
+		return nil, nil, false
+	}
+	return &t, nil, true
+}
+
+func newTestPackage(t *testing.T) *GoPackage {
+	a, err := ParseGoCode("a.go", []byte(packageCodeA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseGoCode("b.go", []byte(packageCodeB))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GoPackage{Name: "p", Files: []*GoFile{a, b}}
+}
+
+func memberNames(members []MemberSpec) map[string]int {
+	names := make(map[string]int)
+	for _, m := range members {
+		names[m.Name()] = m.Depth
+	}
+	return names
+}
+
+func TestMembers(t *testing.T) {
+	pkg := newTestPackage(t)
+	name := "Name"
+	resolver := fakeResolver{
+		"ast.File": {
+			Name:   "File",
+			Fields: []TypeInfoSpec{{Name: &name, Type: "*Ident"}},
+		},
+		"io.Reader": {
+			Name:    "Reader",
+			Methods: []FuncSpec{{Name: "Read", Type: "func(p []byte) (n int, err error)"}},
+		},
+	}
+
+	members := memberNames(pkg.Members("GoFile", true, resolver))
+	want := map[string]int{
+		"File":  0,
+		"Base":  0,
+		"Name":  0, // 遮蔽 Base.Name 和 ast.File.Name
+		"ID":    1,
+		"Close": 1,
+		"Open":  1,
+	}
+	for name, depth := range want {
+		if got, ok := members[name]; !ok || got != depth {
+			t.Errorf("GoFile member %s: depth = %d, found = %v, want %d", name, got, ok, depth)
+		}
+	}
+	if len(members) != len(want) {
+		t.Errorf("GoFile members = %v, want %v", members, want)
+	}
+
+	// 值类型不包括指针接收者的方法
+	if _, ok := memberNames(pkg.Members("GoFile", false, resolver))["Close"]; ok {
+		t.Errorf("value GoFile should not have pointer method Close")
+	}
+
+	// 同一层的同名字段有歧义
+	if _, ok := memberNames(pkg.Members("Both", false, nil))["X"]; ok {
+		t.Errorf("ambiguous field X should not be promoted")
+	}
+
+	members = memberNames(pkg.Members("ReadCloser", false, resolver))
+	for _, name := range []string{"Flush", "Close", "Read"} {
+		if _, ok := members[name]; !ok {
+			t.Errorf("ReadCloser should have method %s: %v", name, members)
+		}
+	}
+	// 其他包的类型通过 resolver 查找
+	members = memberNames(pkg.MembersOf("ast", "File", true, resolver))
+	if _, ok := members["Name"]; !ok || len(members) != 1 {
+		t.Errorf("ast.File members = %v", members)
+	}
+}
+
+func TestTypeSpecEmbeds(t *testing.T) {
+	pkg := newTestPackage(t)
+	gofile, _ := pkg.LookupType("GoFile")
+	if len(gofile.Embeds) != 2 {
+		t.Fatalf("GoFile embeds = %+v", gofile.Embeds)
+	}
+	embed := gofile.Embeds[0]
+	if embed.Type != "*ast.File" || embed.Package != "ast" || embed.Name != "File" || !embed.Pointer {
+		t.Errorf("embed = %+v", embed)
+	}
+
+	rc, _ := pkg.LookupType("ReadCloser")
+	if len(rc.Methods) != 1 || rc.Methods[0].Type != "func() error" {
+		t.Errorf("ReadCloser methods = %+v", rc.Methods)
+	}
+	if len(rc.Embeds) != 2 {
+		t.Errorf("ReadCloser embeds = %+v", rc.Embeds)
+	}
+}
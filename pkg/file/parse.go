@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"go/ast"
+	"go/build/constraint"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"io"
 	"os"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 type GoFile struct {
@@ -18,16 +22,19 @@ type GoFile struct {
 	*token.FileSet
 	Variables    map[string]map[string]TypeInfoSpec
 	Functions    map[string]map[string]FuncSpec
+	Methods      map[string]map[string]FuncSpec // 接收者类型 -> 方法名 -> 方法
 	Types        map[string]map[string]TypeSpec
-	Imports      map[string]ImportSpec
-	buffer       map[Position]byte
-	BlockType    []string
+	Imports      []ImportSpec       // 按源码顺序的全部 import，同一个路径可以用不同的名称导入多次
+	text         text               // 文件内容，用于按行列读取光标附近的字节
+	Scope        *BlockSpec         // 文件的词法作用域树
+	SyntaxErrors scanner.ErrorList  // 语法错误，有错误时 ast 是部分恢复的结果
+	Constraint   constraint.Expr    // 文件的构建约束，没有约束时为 nil
 	scopeIsParse map[Scope]struct{} //保存已经解析过的范围
 }
 
 func (g *GoFile) GetByteByPosition(position Position) (byte, error) {
-	if b, ok := g.buffer[position]; ok {
-		return b, nil
+	if offset, ok := g.text.offset(position.Line, position.Column); ok {
+		return g.text.data[offset], nil
 	}
 	return byte(0), fmt.Errorf("no byte found for position %v", position)
 }
@@ -52,33 +59,23 @@ func (g *GoFile) GetCursorNode(pos Position) ast.Node {
 	return respNode
 }
 
-func (g *GoFile) SetScopeDest(ctx context.Context, start, end token.Pos) {
-	startLine := g.Position(start).Line
-	endLine := g.Position(end).Line
-	for i := startLine - 1; i < endLine; i++ {
-		g.BlockType[i] = getBlockName(ctx)
-	}
-}
-
-// 获取当前光标前的单词
+// 获取当前光标前的单词，包括光标处的字节，光标越过行尾时取到行尾
 func (g *GoFile) GetCursorWord(pos Position) string {
-	var word []byte
-	for ; pos.Column >= 0; pos.Column-- {
-		b := g.buffer[pos]
-		if b == ' ' {
-			return string(word)
-		}
-		word = append(word, b)
+	start, end, ok := g.text.lineRange(pos.Line)
+	if !ok || pos.Column < 0 {
+		return ""
 	}
-	return string(word)
-}
-
-// 返回当前块节点的名字
-func (g *GoFile) GetCursorBlock(position Position) string {
-	if position.Line < 0 || position.Line >= len(g.BlockType) || g.BlockType[position.Line] == "" {
-		return "global"
+	for end > start && (g.text.data[end-1] == '\n' || g.text.data[end-1] == '\r') {
+		end--
+	}
+	end = min(end, start+pos.Column+1)
+	first := end - 1
+	for ; first >= start; first-- {
+		if b := g.text.data[first]; b == ' ' || b == '\t' {
+			break
+		}
 	}
-	return g.BlockType[position.Line-1]
+	return string(g.text.data[first+1 : end])
 }
 
 type Position struct {
@@ -88,44 +85,50 @@ type Position struct {
 }
 
 func ParseGoFile(file *os.File) (*GoFile, error) {
-	var gof = GoFile{
-		Variables:    make(map[string]map[string]TypeInfoSpec),
-		Functions:    make(map[string]map[string]FuncSpec),
-		Types:        make(map[string]map[string]TypeSpec),
-		Imports:      make(map[string]ImportSpec),
-		buffer:       make(map[Position]byte),
-		scopeIsParse: make(map[Scope]struct{}),
-		BlockType:    make([]string, 0),
-	}
-
-	fest := token.NewFileSet()
-	gof.FileSet = fest
 	code, err := io.ReadAll(file)
 	if err != nil {
 		return nil, err
 	}
 
-	x := 0
-	y := 0
-	for _, b := range code {
-		gof.buffer[Position{Filename: file.Name(), Line: y, Column: x}] = b
-		x++
-		if b == '\r' || b == '\n' {
-			y++
-			x = 0
-		}
+	gof, err := ParseGoCode(file.Name(), code)
+	if err != nil {
+		return nil, err
 	}
-	gof.BlockType = make([]string, y+1)
-
 	gof.FileInfo, err = file.Stat()
 	if err != nil {
 		return nil, err
 	}
-	astFile, err := parser.ParseFile(fest, file.Name(), code, parser.AllErrors)
+	return gof, nil
+}
+
+// ParseGoCode 解析文件内容，用于解析编辑器中还没有保存的文件
+func ParseGoCode(filename string, code []byte) (*GoFile, error) {
+	var gof = GoFile{
+		Variables:    make(map[string]map[string]TypeInfoSpec),
+		Functions:    make(map[string]map[string]FuncSpec),
+		Methods:      make(map[string]map[string]FuncSpec),
+		Types:        make(map[string]map[string]TypeSpec),
+		text:         newText(code),
+		scopeIsParse: make(map[Scope]struct{}),
+	}
+
+	fest := token.NewFileSet()
+	gof.FileSet = fest
+
+	// 有语法错误时 parser 仍然返回尽量恢复出来的 ast，保留能解析出来的声明
+	astFile, err := parser.ParseFile(fest, filename, code, parser.AllErrors|parser.ParseComments)
 	if err != nil {
-		return nil, err
+		list, ok := err.(scanner.ErrorList)
+		if !ok || astFile == nil {
+			return nil, err
+		}
+		gof.SyntaxErrors = list
 	}
 	gof.File = astFile
+	gof.Constraint = FileConstraint(filename, astFile)
+	// 格式错误的 //go:build 行不会生效，作为错误报告出来
+	gof.SyntaxErrors = append(gof.SyntaxErrors, gof.buildLineErrors()...)
+	gof.buildScope(gof.File)
 	gof.parse(context.Background(), gof.File)
 	gof.scopeIsParse = nil
 	return &gof, nil
@@ -159,6 +162,8 @@ func (g *GoFile) parseHandle(ctx context.Context, node ast.Node) {
 			g.genDeclHandle(ctx, x)
 		case *ast.AssignStmt:
 			g.assignDeclStmtHandle(ctx, x)
+		case *ast.RangeStmt:
+			g.rangeStmtHandle(ctx, x)
 		case *ast.TypeSpec:
 			g.typeDeclHandle(ctx, x)
 		case *ast.FuncLit:
@@ -183,15 +188,15 @@ func (g *GoFile) registerParse(start, end token.Pos) {
 
 func (g *GoFile) funcLitHandle(ctx context.Context, n *ast.FuncLit) {
 	g.registerParse(n.Pos(), n.End())
-	g.SetScopeDest(ctx, n.Pos(), n.End())
 	log.Info().Msg("funcLitHandle")
 	ctx = withBlockName(ctx, BlockSpecTypeLambda.String())
-	g.parseHandle(ctx, n.Body)
+	if n.Body != nil {
+		g.parseHandle(ctx, n.Body)
+	}
 }
 
 func (g *GoFile) blockDeclHandle(ctx context.Context, block *ast.BlockStmt) {
 	g.registerParse(block.Pos(), block.End())
-	g.SetScopeDest(ctx, block.Pos(), block.End())
 	log.Info().Msg("blockDeclHandle")
 	ctx = withBlockName(ctx, BlockSpecTypeBlock.String())
 	for _, stmt := range block.List {
@@ -202,39 +207,128 @@ func (g *GoFile) blockDeclHandle(ctx context.Context, block *ast.BlockStmt) {
 func (g *GoFile) typeDeclHandle(ctx context.Context, x *ast.TypeSpec) {
 	log.Info().Msg("typeDeclHandle")
 	fields := []TypeInfoSpec{}
-	if structType, ok := x.Type.(*ast.StructType); ok {
-		ctx = withBlockName(ctx, x.Name.Name)
-		for _, field := range structType.Fields.List {
+	var methods []FuncSpec
+	var embeds []EmbedSpec
+	switch t := x.Type.(type) {
+	case *ast.StructType:
+		for _, field := range t.Fields.List {
+			tag, tags := fieldTag(field)
+			// 嵌入字段，字段名称为类型名称
+			if len(field.Names) == 0 {
+				embed, ok := parseEmbed(field.Type)
+				if !ok {
+					continue
+				}
+				embeds = append(embeds, embed)
+				fields = append(fields, TypeInfoSpec{
+					Name:    &embed.Name,
+					Type:    embed.Type,
+					Scope:   Scope{field.Pos(), field.End()},
+					Comment: field.Doc.Text(),
+					Tag:     tag,
+					Tags:    tags,
+				})
+				continue
+			}
 			for _, ident := range field.Names {
 				fieldInfo := TypeInfoSpec{
-					Name:  &ident.Name,
-					Type:  getTypeString(field.Type),
-					Scope: Scope{ident.Pos(), ident.End()},
+					Name:    &ident.Name,
+					Type:    getTypeString(field.Type),
+					Scope:   Scope{ident.Pos(), ident.End()},
+					Comment: field.Doc.Text(),
+					Tag:     tag,
+					Tags:    tags,
 				}
 				fields = append(fields, fieldInfo)
 			}
 		}
+	case *ast.InterfaceType:
+		for _, method := range t.Methods.List {
+			fn, ok := method.Type.(*ast.FuncType)
+			if !ok || len(method.Names) == 0 {
+				// 嵌入的接口，类型约束 ~int | ~string 不是嵌入
+				if embed, ok := parseEmbed(method.Type); ok {
+					embeds = append(embeds, embed)
+				}
+				continue
+			}
+			methods = append(methods, FuncSpec{
+				Name:    method.Names[0].Name,
+				Type:    getTypeString(fn),
+				Params:  parseFieldList(fn.Params),
+				Returns: parseFieldList(fn.Results),
+				Scope:   Scope{method.Pos(), method.End()},
+				Comment: method.Doc.Text(),
+			})
+		}
+	}
+
+	var typeParams []TypeInfoSpec
+	if x.TypeParams != nil {
+		typeParams = parseFieldList(x.TypeParams)
 	}
 
 	g.registerParse(x.Pos(), x.End())
-	g.SetScopeDest(ctx, x.Pos(), x.End())
 	g.withTypeInfo(ctx, TypeSpec{
-		Name:    x.Name.Name,
-		Fields:  fields,
-		Scope:   Scope{x.Pos(), x.End()},
-		Comment: x.Doc.Text(),
-		Type:    getTypeString(x.Type),
+		Name:       x.Name.Name,
+		TypeParams: typeParams,
+		Fields:     fields,
+		Methods:    methods,
+		Embeds:     embeds,
+		Scope:      Scope{x.Pos(), x.End()},
+		Comment:    x.Doc.Text(),
+		Type:       getTypeString(x.Type),
 	})
 }
 
-func (g *GoFile) parseFieldList(ctx context.Context, fields *ast.FieldList) []TypeInfoSpec {
+// parseEmbed 解析嵌入的类型，比如 A *A pkg.A *pkg.A A[T]
+func parseEmbed(expr ast.Expr) (EmbedSpec, bool) {
+	embed := EmbedSpec{Type: getTypeString(expr)}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		embed.Pointer = true
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		embed.Name = t.Name
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			return embed, false
+		}
+		embed.Package = pkg.Name
+		embed.Name = t.Sel.Name
+	default:
+		return embed, false
+	}
+	return embed, true
+}
+
+func parseFieldList(fields *ast.FieldList) []TypeInfoSpec {
 	var fieldlist []TypeInfoSpec
+	if fields == nil {
+		return fieldlist
+	}
 	for _, param := range fields.List {
+		// 没有名称的参数，比如 func() (int, error)
+		if len(param.Names) == 0 {
+			fieldlist = append(fieldlist, TypeInfoSpec{
+				Scope: Scope{param.Pos(), param.End()},
+				Type:  getTypeString(param.Type),
+			})
+			continue
+		}
 		for _, ident := range param.Names {
 			fieldlist = append(fieldlist, TypeInfoSpec{
 				Name:    &ident.Name,
 				Scope:   Scope{ident.Pos(), ident.End()},
-				Type:    ident.Obj.Kind.String(),
+				Type:    getTypeString(param.Type),
 				Comment: "",
 			})
 		}
@@ -244,43 +338,88 @@ func (g *GoFile) parseFieldList(ctx context.Context, fields *ast.FieldList) []Ty
 
 func (g *GoFile) funcDeclHandle(ctx context.Context, n *ast.FuncDecl) {
 	g.registerParse(n.Pos(), n.End())
-	g.SetScopeDest(ctx, n.Pos(), n.End())
 	log.Info().Msg(fmt.Sprintf("funcDeclHandle: %v", n.Name.Name))
-	ctx = withBlockName(ctx, n.Name.Name)
+	// 函数本身注册在外层的块中，函数体使用自己的块名，方法为 Recv.Name
+	recv := parseRecv(n.Recv)
+	blockName := n.Name.Name
+	if recv != nil {
+		blockName = recv.Type + "." + n.Name.Name
+	}
+	bodyCtx := withBlockName(ctx, blockName)
+	typeParams := []TypeInfoSpec{}
 	params := []TypeInfoSpec{}
 	returns := []TypeInfoSpec{}
 
+	if n.Type.TypeParams != nil {
+		typeParams = parseFieldList(n.Type.TypeParams)
+	}
+
 	if n.Type.Params != nil {
-		params = g.parseFieldList(ctx, n.Type.Params)
+		params = parseFieldList(n.Type.Params)
 	}
 
 	if n.Type.Results != nil {
-		returns = g.parseFieldList(ctx, n.Type.Results)
-	}
-	g.withFunction(ctx, FuncSpec{
-		Name:    n.Name.Name,
-		Scope:   Scope{n.Pos(), n.End()},
-		Params:  params,
-		Returns: returns,
-		Comment: n.Doc.Text(),
-	})
+		returns = parseFieldList(n.Type.Results)
+	}
+	fn := FuncSpec{
+		Name:       n.Name.Name,
+		Recv:       recv,
+		Type:       getTypeString(n.Type),
+		TypeParams: typeParams,
+		Scope:      Scope{n.Pos(), n.End()},
+		Params:     params,
+		Returns:    returns,
+		Comment:    n.Doc.Text(),
+	}
+	if fn.Recv != nil {
+		// 不同类型的同名方法不能互相覆盖
+		g.withMethod(fn)
+	} else {
+		g.withFunction(ctx, fn)
+	}
 
-	g.parseHandle(ctx, n.Body)
+	// 没有函数体的声明，或者输入到一半的函数
+	if n.Body != nil {
+		g.parseHandle(bodyCtx, n.Body)
+	}
 }
 
 func (g *GoFile) assignDeclStmtHandle(ctx context.Context, stmt *ast.AssignStmt) {
 	g.registerParse(stmt.Pos(), stmt.End())
-	g.SetScopeDest(ctx, stmt.Pos(), stmt.End())
 	log.Info().Msg("assignDeclStmtHandle")
 	if stmt.Tok != token.DEFINE {
 		return
 	}
 
-	for _, lhs := range stmt.Lhs {
+	for i, lhs := range stmt.Lhs {
 		if ident, ok := lhs.(*ast.Ident); ok {
 			g.withVariable(ctx, TypeInfoSpec{
 				Name: &ident.Name,
-				Type: getTypeString(ident),
+				Type: g.inferAssign(i, len(stmt.Lhs), stmt.Rhs, nil, 0),
+				Scope: Scope{
+					ident.Pos(),
+					ident.End(),
+				},
+			})
+		}
+	}
+}
+
+func (g *GoFile) rangeStmtHandle(ctx context.Context, stmt *ast.RangeStmt) {
+	g.registerParse(stmt.Pos(), stmt.End())
+	if stmt.Tok != token.DEFINE {
+		return
+	}
+
+	k, v := RangeTypes(g.inferOne(stmt.X, nil, 0))
+	for _, kv := range []struct {
+		expr ast.Expr
+		typ  string
+	}{{stmt.Key, k}, {stmt.Value, v}} {
+		if ident, ok := kv.expr.(*ast.Ident); ok {
+			g.withVariable(ctx, TypeInfoSpec{
+				Name: &ident.Name,
+				Type: kv.typ,
 				Scope: Scope{
 					ident.Pos(),
 					ident.End(),
@@ -292,7 +431,6 @@ func (g *GoFile) assignDeclStmtHandle(ctx context.Context, stmt *ast.AssignStmt)
 
 func (g *GoFile) genDeclHandle(ctx context.Context, x *ast.GenDecl) {
 	g.registerParse(x.Pos(), x.End())
-	g.SetScopeDest(ctx, x.Pos(), x.End())
 	log.Info().Msg("genDeclHandle")
 	if x.Tok == token.VAR || x.Tok == token.CONST {
 		for _, spec := range x.Specs {
@@ -316,16 +454,19 @@ func (g *GoFile) genDeclHandle(ctx context.Context, x *ast.GenDecl) {
 		for _, spec := range x.Specs {
 			if vspec, ok := spec.(*ast.ImportSpec); ok {
 				path := strings.Trim(vspec.Path.Value, "\"")
-				names := strings.Split(path, "/")
-				name := names[len(names)-1]
-				g.withImports(ctx, ImportSpec{
-					Name: name,
+				n := ImportSpec{
+					Name: importPathToAssumedName(path),
 					Path: path,
 					Scope: Scope{
 						Start: vspec.Pos(),
 						End:   vspec.End(),
 					},
-				})
+				}
+				if vspec.Name != nil {
+					n.Alias = vspec.Name.Name
+					n.Name = vspec.Name.Name
+				}
+				g.withImports(n)
 			}
 		}
 	}
@@ -365,12 +506,103 @@ func (g *GoFile) withFunction(ctx context.Context, n FuncSpec) {
 	g.Functions[dest][n.Name] = n
 }
 
-func (g *GoFile) withImports(ctx context.Context, n ImportSpec) {
-	dest := getBlockName(ctx)
-	if _, ok := g.Imports[dest]; !ok {
-		g.Imports = make(map[string]ImportSpec)
+func (g *GoFile) withMethod(n FuncSpec) {
+	recv := n.Recv.Type
+	if _, ok := g.Methods[recv]; !ok {
+		g.Methods[recv] = make(map[string]FuncSpec)
+	}
+	g.Methods[recv][n.Name] = n
+}
+
+// parseRecv 解析方法的接收者，比如 (a *A) (A) (c *Cache[T])
+func parseRecv(recv *ast.FieldList) *RecvSpec {
+	if recv == nil || len(recv.List) == 0 {
+		return nil
 	}
-	g.Imports[dest] = n
+	field := recv.List[0]
+	spec := &RecvSpec{}
+	if len(field.Names) > 0 {
+		spec.Name = &field.Names[0].Name
+	}
+
+	typ := field.Type
+	if paren, ok := typ.(*ast.ParenExpr); ok {
+		typ = paren.X
+	}
+	if star, ok := typ.(*ast.StarExpr); ok {
+		spec.Pointer = true
+		typ = star.X
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	spec.Type = getTypeString(typ)
+	return spec
+}
+
+// MethodSet 返回类型的方法集，pointer 为 true 时包括指针接收者的方法
+func (g *GoFile) MethodSet(typeName string, pointer bool) []FuncSpec {
+	var methods []FuncSpec
+	for _, method := range g.Methods[typeName] {
+		if method.Recv.Pointer && !pointer {
+			continue
+		}
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+	return methods
+}
+
+func (g *GoFile) withImports(n ImportSpec) {
+	g.Imports = append(g.Imports, n)
+}
+
+// ImportsOf 文件中 import 路径为 path 的全部 import
+func (g *GoFile) ImportsOf(path string) []ImportSpec {
+	var imports []ImportSpec
+	for _, n := range g.Imports {
+		if n.Path == path {
+			imports = append(imports, n)
+		}
+	}
+	return imports
+}
+
+// ResolveImportNames 使用真实的包名替换没有别名的 import 的名称，
+// 包名经常和路径的最后一段不同，比如 go-lsp 的包名是 lsp。lookup 找不到时保留推测的名称
+func (g *GoFile) ResolveImportNames(lookup func(path string) (string, bool)) {
+	for i, n := range g.Imports {
+		if n.Alias != "" {
+			continue
+		}
+		name, ok := lookup(n.Path)
+		if !ok || name == n.Name {
+			continue
+		}
+		if g.Scope != nil {
+			if decl, ok := g.Scope.Decls[n.Name]; ok && decl.Type == DeclSpecTypeImport {
+				delete(g.Scope.Decls, n.Name)
+				decl.Name = name
+				g.Scope.Decls[name] = decl
+			}
+		}
+		g.Imports[i].Name = name
+	}
+}
+
+// ImportByName 根据文件中引用包的名称查找 import
+func (g *GoFile) ImportByName(name string) (ImportSpec, bool) {
+	for _, n := range g.Imports {
+		if n.Name == name && !n.IsDot() && !n.IsBlank() {
+			return n, true
+		}
+	}
+	return ImportSpec{}, false
 }
 
 func (g *GoFile) withTypeInfo(ctx context.Context, n TypeSpec) {
@@ -381,18 +613,87 @@ func (g *GoFile) withTypeInfo(ctx context.Context, n TypeSpec) {
 	g.Types[dest][n.Name] = n
 }
 
-// getTypeString 辅助函数，将 ast 类型节点转换为字符串
-func getTypeString(node ast.Node) string {
-	switch n := node.(type) {
-	case *ast.Ident:
-		return n.Name
-	case *ast.ArrayType:
-		return "[]" + getTypeString(n.Elt)
-	case *ast.StructType:
-		return "struct{}"
-	case *ast.FuncType:
-		return "func()"
-	default:
-		return fmt.Sprintf("%T", n)
+// importPathToAssumedName 根据 import 路径推测包名，规则和 goimports 相同:
+// 去掉 .v3 这样的版本后缀，路径以 /v2 结尾时使用上一段，去掉 go- 前缀和 -go 后缀，
+// 比如 gopkg.in/yaml.v3 -> yaml，github.com/a/b/v2 -> b，pkg.nimblebun.works/go-lsp -> lsp
+func importPathToAssumedName(path string) string {
+	path = strings.Trim(path, "\"")
+	names := strings.Split(path, "/")
+	name := names[len(names)-1]
+	if len(names) > 1 && isVersionSuffix(name) {
+		name = names[len(names)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isVersionSuffix(name[i+1:]) {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// isVersionSuffix v2 v10
+func isVersionSuffix(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
 	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IdentPrefix 返回光标前正在输入的标识符。前面是 x. 时 dot 为 true，selector 为 x 的文本，
+// x 不是 a.b.c 这种由标识符组成的链时 selector 为空
+func (g *GoFile) IdentPrefix(pos Position) (prefix string, selector string, dot bool) {
+	start, end, ok := g.text.lineRange(pos.Line)
+	if !ok || pos.Column < 0 {
+		return "", "", false
+	}
+	end = min(end, start+pos.Column)
+	i := end
+	for i > start && isIdentByte(g.text.data[i-1]) {
+		i--
+	}
+	prefix = string(g.text.data[i:end])
+	if i == start || g.text.data[i-1] != '.' {
+		return prefix, "", false
+	}
+	j := i - 1
+	for j > start && (isIdentByte(g.text.data[j-1]) || g.text.data[j-1] == '.') {
+		j--
+	}
+	// x 是调用、索引等表达式
+	if j > start && (g.text.data[j-1] == ')' || g.text.data[j-1] == ']' || g.text.data[j-1] == '}') {
+		return prefix, "", true
+	}
+	return prefix, string(g.text.data[j : i-1]), true
+}
+
+// IdentAfter 从 pos 开始的标识符，pos 不是标识符的开始时返回空
+func (g *GoFile) IdentAfter(pos Position) string {
+	start, end, ok := g.text.lineRange(pos.Line)
+	if !ok || pos.Column < 0 || start+pos.Column > end {
+		return ""
+	}
+	i := start + pos.Column
+	if i > start && isIdentByte(g.text.data[i-1]) {
+		return ""
+	}
+	j := i
+	for j < end && isIdentByte(g.text.data[j]) {
+		j++
+	}
+	return string(g.text.data[i:j])
+}
+
+// isIdentByte 标识符中的字节，非 ascii 字节都当作 unicode 字母
+func isIdentByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || b >= 0x80
 }
@@ -0,0 +1,526 @@
+package file
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// ScopeAt 返回包含 pos 的最内层作用域
+func (g *GoFile) ScopeAt(pos token.Pos) *BlockSpec {
+	scope := g.Scope
+	if scope == nil {
+		return nil
+	}
+	for {
+		var next *BlockSpec
+		for _, child := range scope.Children {
+			if child.Scope.Start <= pos && pos <= child.Scope.End {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return scope
+		}
+		scope = next
+	}
+}
+
+// LookupVisible 按 go 的遮蔽规则查找 pos 处可见的标识符
+func (g *GoFile) LookupVisible(name string, pos token.Pos) (*DeclSpec, *BlockSpec) {
+	for scope := g.ScopeAt(pos); scope != nil; scope = scope.Parent {
+		if decl, ok := scope.Decls[name]; ok && decl.Visible <= pos {
+			return decl, scope
+		}
+	}
+	return nil, nil
+}
+
+// TokenPos 将从 0 开始的行列位置转换为 token.Pos
+func (g *GoFile) TokenPos(position Position) token.Pos {
+	if g.FileSet == nil || g.File == nil {
+		return token.NoPos
+	}
+	tf := g.FileSet.File(g.File.Pos())
+	if tf == nil || position.Line < 0 || position.Line >= tf.LineCount() {
+		return token.NoPos
+	}
+	offset := tf.Offset(tf.LineStart(position.Line+1)) + position.Column
+	if offset > tf.Size() {
+		offset = tf.Size()
+	}
+	return tf.Pos(offset)
+}
+
+func newBlockSpec(typ BlockSpecType, parent *BlockSpec, start, end token.Pos) *BlockSpec {
+	scope := &BlockSpec{
+		Type:   typ,
+		Scope:  Scope{Start: start, End: end},
+		Parent: parent,
+		Decls:  make(map[string]*DeclSpec),
+	}
+	if parent != nil {
+		parent.Children = append(parent.Children, scope)
+	}
+	return scope
+}
+
+func (b *BlockSpec) declare(ident *ast.Ident, typ DeclSpecType, visible token.Pos, node ast.Node) {
+	if ident == nil || ident.Name == "_" {
+		return
+	}
+	// `a, err := f(); b, err := g()` 中的 err 是同一个变量
+	if _, ok := b.Decls[ident.Name]; ok {
+		return
+	}
+	b.Decls[ident.Name] = &DeclSpec{
+		Name:    ident.Name,
+		Type:    typ,
+		Pos:     ident.Pos(),
+		Visible: visible,
+		Node:    node,
+	}
+}
+
+// buildScope 遍历整个文件构建作用域树
+func (g *GoFile) buildScope(file *ast.File) {
+	g.Scope = newBlockSpec(BlockSpecTypeFile, nil, file.FileStart, file.FileEnd)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			// 方法不在包作用域中声明
+			if d.Recv == nil {
+				g.Scope.declare(d.Name, DeclSpecTypeFunc, token.NoPos, d)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				g.declareSpec(g.Scope, d.Tok, spec)
+			}
+		}
+	}
+	for _, decl := range file.Decls {
+		g.walkScope(g.Scope, decl)
+	}
+}
+
+// declareSpec 声明 var/const/type/import，包级别的声明在整个文件可见
+func (g *GoFile) declareSpec(scope *BlockSpec, tok token.Token, spec ast.Spec) {
+	visible := token.NoPos
+	switch s := spec.(type) {
+	case *ast.ValueSpec:
+		typ := DeclSpecTypeVar
+		if tok == token.CONST {
+			typ = DeclSpecTypeConst
+		}
+		if scope.Type != BlockSpecTypeFile {
+			visible = s.End()
+		}
+		for _, name := range s.Names {
+			scope.declare(name, typ, visible, s)
+		}
+	case *ast.TypeSpec:
+		if scope.Type != BlockSpecTypeFile {
+			visible = s.Name.Pos()
+		}
+		scope.declare(s.Name, DeclSpecTypeType, visible, s)
+	case *ast.ImportSpec:
+		// . 和 _ 不引入名称
+		if s.Name != nil && (s.Name.Name == "." || s.Name.Name == "_") {
+			return
+		}
+		name := s.Name
+		if name == nil {
+			name = ast.NewIdent(importPathToAssumedName(s.Path.Value))
+			name.NamePos = s.Path.Pos()
+		}
+		scope.declare(name, DeclSpecTypeImport, token.NoPos, s)
+	}
+}
+
+func (g *GoFile) declareFieldList(scope *BlockSpec, fields *ast.FieldList, typ DeclSpecType) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			scope.declare(name, typ, scope.Scope.Start, field)
+		}
+	}
+}
+
+func (g *GoFile) walkFunc(parent *BlockSpec, typ BlockSpecType, recv *ast.FieldList, fn *ast.FuncType, body *ast.BlockStmt, start token.Pos) {
+	if body == nil {
+		return
+	}
+	scope := newBlockSpec(typ, parent, start, body.End())
+	g.declareFieldList(scope, recv, DeclSpecTypeParam)
+	g.declareRecvTypeParams(scope, recv)
+	g.declareFieldList(scope, fn.TypeParams, DeclSpecTypeType)
+	g.declareFieldList(scope, fn.Params, DeclSpecTypeParam)
+	g.declareFieldList(scope, fn.Results, DeclSpecTypeParam)
+	// 函数体和参数在同一个作用域
+	g.walkStmts(scope, body.List)
+}
+
+// declareRecvTypeParams 声明泛型接收者的类型参数，比如 func (c *Cache[K, V]) 中的 K V
+func (g *GoFile) declareRecvTypeParams(scope *BlockSpec, recv *ast.FieldList) {
+	if recv == nil || len(recv.List) == 0 {
+		return
+	}
+	typ := recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	var indices []ast.Expr
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			scope.declare(ident, DeclSpecTypeType, scope.Scope.Start, recv)
+		}
+	}
+}
+
+func (g *GoFile) walkStmts(scope *BlockSpec, stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		g.walkScope(scope, stmt)
+	}
+}
+
+func (g *GoFile) walkBlock(parent *BlockSpec, typ BlockSpecType, block *ast.BlockStmt) {
+	if block == nil {
+		return
+	}
+	scope := newBlockSpec(typ, parent, block.Pos(), block.End())
+	g.walkStmts(scope, block.List)
+}
+
+func (g *GoFile) walkScope(scope *BlockSpec, node ast.Node) {
+	if node == nil {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncDecl:
+			g.walkFunc(scope, BlockSpecTypeFunc, x.Recv, x.Type, x.Body, x.Type.Pos())
+			return false
+		case *ast.FuncLit:
+			g.walkFunc(scope, BlockSpecTypeLambda, nil, x.Type, x.Body, x.Pos())
+			return false
+		case *ast.BlockStmt:
+			g.walkBlock(scope, BlockSpecTypeBlock, x)
+			return false
+		case *ast.IfStmt:
+			ifScope := newBlockSpec(BlockSpecTypeIf, scope, x.Pos(), x.End())
+			g.walkScope(ifScope, x.Init)
+			g.walkScope(ifScope, x.Cond)
+			g.walkBlock(ifScope, BlockSpecTypeBlock, x.Body)
+			g.walkScope(ifScope, x.Else)
+			return false
+		case *ast.ForStmt:
+			forScope := newBlockSpec(BlockSpecTypeFor, scope, x.Pos(), x.End())
+			g.walkScope(forScope, x.Init)
+			g.walkScope(forScope, x.Cond)
+			g.walkScope(forScope, x.Post)
+			g.walkBlock(forScope, BlockSpecTypeBlock, x.Body)
+			return false
+		case *ast.RangeStmt:
+			g.walkScope(scope, x.X)
+			forScope := newBlockSpec(BlockSpecTypeFor, scope, x.Pos(), x.End())
+			if x.Tok == token.DEFINE {
+				for _, expr := range []ast.Expr{x.Key, x.Value} {
+					if ident, ok := expr.(*ast.Ident); ok {
+						forScope.declare(ident, DeclSpecTypeVar, x.Body.Pos(), x)
+					}
+				}
+			}
+			g.walkBlock(forScope, BlockSpecTypeBlock, x.Body)
+			return false
+		case *ast.SwitchStmt:
+			switchScope := newBlockSpec(BlockSpecTypeSwitch, scope, x.Pos(), x.End())
+			g.walkScope(switchScope, x.Init)
+			g.walkScope(switchScope, x.Tag)
+			g.walkClauses(switchScope, x.Body, nil)
+			return false
+		case *ast.TypeSwitchStmt:
+			switchScope := newBlockSpec(BlockSpecTypeSwitch, scope, x.Pos(), x.End())
+			g.walkScope(switchScope, x.Init)
+			// switch v := x.(type) 中的 v 在每个 case 中分别声明
+			var symbol *ast.Ident
+			if assign, ok := x.Assign.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 {
+				symbol, _ = assign.Lhs[0].(*ast.Ident)
+				g.walkScope(switchScope, assign.Rhs[0])
+			}
+			g.walkClauses(switchScope, x.Body, symbol)
+			return false
+		case *ast.SelectStmt:
+			selectScope := newBlockSpec(BlockSpecTypeSelect, scope, x.Pos(), x.End())
+			g.walkClauses(selectScope, x.Body, nil)
+			return false
+		case *ast.AssignStmt:
+			for _, rhs := range x.Rhs {
+				g.walkScope(scope, rhs)
+			}
+			if x.Tok == token.DEFINE {
+				for _, lhs := range x.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						scope.declare(ident, DeclSpecTypeVar, x.End(), x)
+					}
+				}
+			}
+			return false
+		case *ast.GenDecl:
+			for _, spec := range x.Specs {
+				g.declareSpec(scope, x.Tok, spec)
+				if vspec, ok := spec.(*ast.ValueSpec); ok {
+					for _, value := range vspec.Values {
+						g.walkScope(scope, value)
+					}
+				}
+			}
+			return false
+		}
+		return true
+	})
+}
+
+func (g *GoFile) walkClauses(parent *BlockSpec, body *ast.BlockStmt, symbol *ast.Ident) {
+	if body == nil {
+		return
+	}
+	for i, stmt := range body.List {
+		// case 的范围延伸到下一个 case 或者右括号，光标在 case 末尾的空行时也属于该 case
+		end := body.Rbrace
+		if i+1 < len(body.List) {
+			end = body.List[i+1].Pos() - 1
+		}
+		switch clause := stmt.(type) {
+		case *ast.CaseClause:
+			scope := newBlockSpec(BlockSpecTypeCase, parent, clause.Pos(), end)
+			for _, expr := range clause.List {
+				g.walkScope(parent, expr)
+			}
+			if symbol != nil {
+				scope.declare(symbol, DeclSpecTypeVar, clause.Colon, clause)
+			}
+			g.walkStmts(scope, clause.Body)
+		case *ast.CommClause:
+			scope := newBlockSpec(BlockSpecTypeCase, parent, clause.Pos(), end)
+			g.walkScope(scope, clause.Comm)
+			// case v := <-ch: 中的 v 从冒号之后可见
+			if assign, ok := clause.Comm.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+				for _, lhs := range assign.Lhs {
+					ident, ok := lhs.(*ast.Ident)
+					if !ok {
+						continue
+					}
+					if decl, ok := scope.Decls[ident.Name]; ok {
+						decl.Visible = clause.Colon
+					}
+				}
+			}
+			g.walkStmts(scope, clause.Body)
+		}
+	}
+}
+
+// IdentAt 返回 pos 处的标识符，以及包含它的选择器表达式 x.Sel(不是选择器时为 nil)
+func (g *GoFile) IdentAt(pos token.Pos) (*ast.Ident, *ast.SelectorExpr) {
+	var ident *ast.Ident
+	var selector *ast.SelectorExpr
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || ident != nil {
+			return false
+		}
+		if pos < n.Pos() || pos > n.End() {
+			return false
+		}
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			if x.Sel.Pos() <= pos && pos <= x.Sel.End() {
+				ident, selector = x.Sel, x
+				return false
+			}
+		case *ast.Ident:
+			ident = x
+			return false
+		}
+		return true
+	})
+	return ident, selector
+}
+
+// DeclOf 返回标识符的声明，标识符本身是声明时返回它自己的声明
+func (g *GoFile) DeclOf(ident *ast.Ident) (*DeclSpec, *BlockSpec) {
+	for scope := g.ScopeAt(ident.Pos()); scope != nil; scope = scope.Parent {
+		if decl, ok := scope.Decls[ident.Name]; ok && decl.Pos == ident.Pos() {
+			return decl, scope
+		}
+	}
+	return g.LookupVisible(ident.Name, ident.Pos())
+}
+
+// DeclString 返回声明的 go 语法描述，比如 var a int、func F(a int) error、type T struct{...}
+func (g *GoFile) DeclString(decl *DeclSpec, resolver FuncResolver) string {
+	switch decl.Type {
+	case DeclSpecTypeVar, DeclSpecTypeParam:
+		return strings.TrimSpace("var " + decl.Name + " " + g.inferDecl(decl, resolver, 0))
+	case DeclSpecTypeConst:
+		return strings.TrimSpace("const " + decl.Name + " " + g.inferDecl(decl, resolver, 0))
+	case DeclSpecTypeFunc:
+		return "func " + decl.Name + strings.TrimPrefix(g.inferDecl(decl, resolver, 0), "func")
+	case DeclSpecTypeType:
+		if t, ok := g.Types["global"][decl.Name]; ok {
+			return "type " + t.Decl()
+		}
+		if spec, ok := decl.Node.(*ast.TypeSpec); ok {
+			return "type " + decl.Name + " " + getTypeString(spec.Type)
+		}
+		return "type " + decl.Name
+	case DeclSpecTypeImport:
+		if spec, ok := decl.Node.(*ast.ImportSpec); ok {
+			return "package " + decl.Name + " (" + spec.Path.Value + ")"
+		}
+	}
+	return decl.Name
+}
+
+// FieldOf 标识符是结构体字段的名称时返回字段
+func (g *GoFile) FieldOf(ident *ast.Ident) *ast.Field {
+	var field *ast.Field
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || field != nil || ident.Pos() < n.Pos() || ident.End() > n.End() {
+			return false
+		}
+		if f, ok := n.(*ast.Field); ok {
+			for _, name := range f.Names {
+				if name == ident {
+					field = f
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return field
+}
+
+// FieldString 返回字段的 go 语法描述，包括标签，比如 field Name string `json:"name"`
+func FieldString(name string, field *ast.Field) string {
+	s := "field " + name + " " + getTypeString(field.Type)
+	if field.Tag != nil {
+		s += " " + field.Tag.Value
+	}
+	return s
+}
+
+// VisibleDecls 返回 pos 处可见的全部声明，内层作用域的声明遮蔽外层的同名声明
+func (g *GoFile) VisibleDecls(pos token.Pos) []*DeclSpec {
+	var decls []*DeclSpec
+	seen := make(map[string]bool)
+	for scope := g.ScopeAt(pos); scope != nil; scope = scope.Parent {
+		for name, decl := range scope.Decls {
+			if seen[name] || decl.Visible > pos || name == "_" {
+				continue
+			}
+			seen[name] = true
+			decls = append(decls, decl)
+		}
+	}
+	return decls
+}
+
+// SelectorBase 返回选择器 x.f 中的 x，dot 为 . 的位置。输入到一半的 x. 也会被解析为选择器
+func (g *GoFile) SelectorBase(dot token.Pos) ast.Expr {
+	var base ast.Expr
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || base != nil || dot < n.Pos() || dot > n.End() {
+			return false
+		}
+		if sel, ok := n.(*ast.SelectorExpr); ok && sel.X.End() == dot {
+			base = sel.X
+			return false
+		}
+		return true
+	})
+	return base
+}
+
+// StartsStatement expr 是否在语句列表中某个语句的开头，输入到一半的 x. 后面是下一行的语句时，两行会被解析为一个语句
+func (g *GoFile) StartsStatement(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || found || expr.Pos() < n.Pos() || expr.Pos() > n.End() {
+			return false
+		}
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		}
+		for _, stmt := range list {
+			found = found || stmt.Pos() == expr.Pos()
+		}
+		return !found
+	})
+	return found
+}
+
+// NodeText 返回节点的源码
+func (g *GoFile) NodeText(node ast.Node) string {
+	tf := g.FileSet.File(node.Pos())
+	if tf == nil || !node.End().IsValid() {
+		return ""
+	}
+	start, end := tf.Offset(node.Pos()), tf.Offset(node.End())
+	if start < 0 || end > len(g.text.data) || start > end {
+		return ""
+	}
+	return string(g.text.data[start:end])
+}
+
+// PositionOf 将 token.Pos 转换为从 0 开始的行列
+func (g *GoFile) PositionOf(pos token.Pos) Position {
+	return g.position(pos)
+}
+
+// EnclosingFunc 返回包含 pos 的最内层函数，包括函数字面量，只有 Params 和 Returns 等签名信息
+func (g *GoFile) EnclosingFunc(pos token.Pos) *FuncSpec {
+	var fn *FuncSpec
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		if n == nil || n != g.File && (pos < n.Pos() || pos >= n.End()) {
+			return false
+		}
+		var typ *ast.FuncType
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			typ, body = n.Type, n.Body
+		case *ast.FuncLit:
+			typ, body = n.Type, n.Body
+		}
+		if typ != nil && body != nil && body.Pos() < pos {
+			fn = &FuncSpec{
+				Type:    getTypeString(typ),
+				Params:  parseFieldList(typ.Params),
+				Returns: parseFieldList(typ.Results),
+				Scope:   Scope{n.Pos(), n.End()},
+			}
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				fn.Name = decl.Name.Name
+				fn.Recv = parseRecv(decl.Recv)
+			}
+		}
+		return true
+	})
+	return fn
+}
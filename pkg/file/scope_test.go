@@ -0,0 +1,310 @@
+package file
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const scopeCode = `package main
+
+import (
+	"fmt"
+	lg "log"
+)
+
+var global = 1
+
+func main(arg int) {
+	x := 1 /*x1*/
+	if y := x; y > 0 {
+		x := x + 1 /*x2*/
+		_ = y /*if*/
+	}
+	switch v := any(x).(type) {
+	case int:
+		_ = v /*case-int*/
+	case string:
+		_ = v /*case-string*/
+	}
+	for i, s := range []string{} {
+		_ = i /*range*/
+	}
+	fn := func(p string) {
+		_ = p /*lambda*/
+	}
+	fn("")
+	fmt.Println(lg.Flags()) /*end*/
+}
+`
+
+func markPos(t *testing.T, gf *GoFile, mark string) token.Pos {
+	offset := strings.Index(scopeCode, "/*"+mark+"*/")
+	if offset < 0 {
+		t.Fatalf("mark %s not found", mark)
+	}
+	return gf.FileSet.File(gf.File.Pos()).Pos(offset)
+}
+
+func TestScopeAt(t *testing.T) {
+	gf, err := ParseGoCode("main.go", []byte(scopeCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mark string
+		want BlockSpecType
+	}{
+		{"x1", BlockSpecTypeFunc},
+		{"x2", BlockSpecTypeBlock},
+		{"case-int", BlockSpecTypeCase},
+		{"range", BlockSpecTypeBlock},
+		{"lambda", BlockSpecTypeLambda},
+		{"end", BlockSpecTypeFunc},
+	}
+	for _, tt := range tests {
+		scope := gf.ScopeAt(markPos(t, gf, tt.mark))
+		if scope.Type != tt.want {
+			t.Errorf("%s: scope = %v, want %v", tt.mark, scope.Type, tt.want)
+		}
+	}
+
+	if gf.ScopeAt(gf.File.Package).Type != BlockSpecTypeFile {
+		t.Errorf("package clause should be in file scope")
+	}
+}
+
+func TestLookupVisible(t *testing.T) {
+	gf, err := ParseGoCode("main.go", []byte(scopeCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		mark  string
+		found bool
+		scope BlockSpecType
+		typ   DeclSpecType
+	}{
+		{"x", "x1", true, BlockSpecTypeFunc, DeclSpecTypeVar},
+		// x := x + 1 的右侧引用外层的 x，声明之后才是内层的 x
+		{"x", "x2", true, BlockSpecTypeBlock, DeclSpecTypeVar},
+		{"y", "if", true, BlockSpecTypeIf, DeclSpecTypeVar},
+		{"y", "end", false, 0, 0},
+		{"v", "case-int", true, BlockSpecTypeCase, DeclSpecTypeVar},
+		{"v", "case-string", true, BlockSpecTypeCase, DeclSpecTypeVar},
+		{"s", "range", true, BlockSpecTypeFor, DeclSpecTypeVar},
+		{"p", "lambda", true, BlockSpecTypeLambda, DeclSpecTypeParam},
+		{"fn", "lambda", false, 0, 0},
+		{"arg", "lambda", true, BlockSpecTypeFunc, DeclSpecTypeParam},
+		{"global", "end", true, BlockSpecTypeFile, DeclSpecTypeVar},
+		{"main", "end", true, BlockSpecTypeFile, DeclSpecTypeFunc},
+		{"fmt", "end", true, BlockSpecTypeFile, DeclSpecTypeImport},
+		{"lg", "end", true, BlockSpecTypeFile, DeclSpecTypeImport},
+		{"log", "end", false, 0, 0},
+	}
+	for _, tt := range tests {
+		decl, scope := gf.LookupVisible(tt.name, markPos(t, gf, tt.mark))
+		if (decl != nil) != tt.found {
+			t.Errorf("%s at %s: found = %v, want %v", tt.name, tt.mark, decl != nil, tt.found)
+			continue
+		}
+		if decl == nil {
+			continue
+		}
+		if scope.Type != tt.scope || decl.Type != tt.typ {
+			t.Errorf("%s at %s: got %v/%v, want %v/%v", tt.name, tt.mark, scope.Type, decl.Type, tt.scope, tt.typ)
+		}
+	}
+
+	// x := x + 1 右侧的 x 是外层的 x
+	rhs := markPos(t, gf, "x2") - token.Pos(len("x + 1 "))
+	decl, scope := gf.LookupVisible("x", rhs)
+	if decl == nil || scope.Type != BlockSpecTypeFunc {
+		t.Errorf("rhs x should resolve to the outer x")
+	}
+}
+
+func TestDeclString(t *testing.T) {
+	gf, err := ParseGoCode("main.go", []byte(scopeCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mark 前面紧挨着的标识符
+	identBefore := func(mark string) token.Pos {
+		offset := strings.Index(scopeCode, "/*"+mark+"*/")
+		prefix := strings.TrimRight(scopeCode[:offset], " ")
+		return gf.FileSet.File(gf.File.Pos()).Pos(len(prefix) - 1)
+	}
+
+	tests := []struct {
+		mark string
+		want string
+	}{
+		{"if", "var y int"},
+		{"lambda", "var p string"},
+		{"case-string", "var v string"},
+	}
+	for _, tt := range tests {
+		ident, _ := gf.IdentAt(identBefore(tt.mark))
+		if ident == nil {
+			t.Fatalf("%s: no ident", tt.mark)
+		}
+		decl, _ := gf.DeclOf(ident)
+		if decl == nil {
+			t.Fatalf("%s: %s not declared", tt.mark, ident.Name)
+		}
+		if got := gf.DeclString(decl, nil); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.mark, got, tt.want)
+		}
+	}
+
+	ident, selector := gf.IdentAt(identBefore("end") - 3)
+	if ident == nil || selector == nil || ident.Name != "Flags" {
+		t.Fatalf("selector ident: got %v", ident)
+	}
+}
+
+func TestVisibleDecls(t *testing.T) {
+	gf, err := ParseGoCode("main.go", []byte(scopeCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(mark string) map[string]DeclSpecType {
+		m := make(map[string]DeclSpecType)
+		for _, decl := range gf.VisibleDecls(markPos(t, gf, mark)) {
+			if _, ok := m[decl.Name]; ok {
+				t.Errorf("%s: %s returned twice", mark, decl.Name)
+			}
+			m[decl.Name] = decl.Type
+		}
+		return m
+	}
+
+	got := names("lambda")
+	for _, name := range []string{"p", "x", "arg", "global", "main", "fmt", "lg"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("lambda: %s not visible", name)
+		}
+	}
+	for _, name := range []string{"y", "v", "i", "s", "fn"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("lambda: %s should not be visible", name)
+		}
+	}
+	if got["lg"] != DeclSpecTypeImport || got["arg"] != DeclSpecTypeParam {
+		t.Errorf("lambda: wrong decl types %v", got)
+	}
+}
+
+func TestIdentPrefix(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\tfmt.Pri\n\tfoo\n\ta.b.Na\n\tf().x\n\tx.\n}\n"
+	gf, err := ParseGoCode("main.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line, column     int
+		prefix, selector string
+		dot              bool
+	}{
+		{3, 8, "Pri", "fmt", true},
+		{3, 5, "", "fmt", true},
+		{3, 4, "fmt", "", false},
+		{4, 4, "foo", "", false},
+		{4, 2, "f", "", false},
+		{5, 7, "Na", "a.b", true},
+		{6, 6, "x", "", true},
+		{7, 3, "", "x", true},
+		{0, 0, "", "", false},
+	}
+	for _, tt := range tests {
+		prefix, selector, dot := gf.IdentPrefix(Position{Line: tt.line, Column: tt.column})
+		if prefix != tt.prefix || selector != tt.selector || dot != tt.dot {
+			t.Errorf("IdentPrefix(%d, %d) = %q, %q, %v, want %q, %q, %v",
+				tt.line, tt.column, prefix, selector, dot, tt.prefix, tt.selector, tt.dot)
+		}
+	}
+}
+
+func TestIdentAfter(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\tfmt.Pri\n\tfoo\n}\n"
+	gf, err := ParseGoCode("main.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line, column int
+		want         string
+	}{
+		{3, 1, "fmt"},
+		{3, 5, "Pri"},
+		{3, 2, ""}, // 不是标识符的开始
+		{4, 1, "foo"},
+		{4, 4, ""},
+		{9, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := gf.IdentAfter(Position{Line: tt.line, Column: tt.column}); got != tt.want {
+			t.Errorf("IdentAfter(%d, %d) = %q, want %q", tt.line, tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorBase(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\ta.b.Na\n\tf().x\n\tx.\n}\n"
+	gf, _ := ParseGoCode("main.go", []byte(code))
+	tests := []struct {
+		line, column int // . 后面的位置
+		want         string
+	}{
+		{3, 3, "a"},
+		{3, 5, "a.b"},
+		{4, 5, "f()"},
+		{5, 3, "x"},
+		{3, 2, ""},
+	}
+	for _, tt := range tests {
+		dot := gf.TokenPos(Position{Line: tt.line, Column: tt.column}) - 1
+		got := ""
+		if base := gf.SelectorBase(dot); base != nil {
+			got = types.ExprString(base)
+		}
+		if got != tt.want {
+			t.Errorf("SelectorBase(%d, %d) = %q, want %q", tt.line, tt.column, got, tt.want)
+		}
+	}
+}
+
+func TestStartsStatement(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\terr.re\n\tx := s.le\n\tif ok {\n\t\tv.\n\t\ty := 1\n\t}\n\tswitch {\n\tcase true:\n\t\tm[k].k\n\t}\n}\n"
+	gf, _ := ParseGoCode("main.go", []byte(code))
+	tests := []struct {
+		line, column int // . 的位置
+		text         string
+		want         bool
+	}{
+		{3, 4, "err", true},
+		{4, 7, "s", false},
+		{6, 3, "v", true},
+		{11, 6, "m[k]", true},
+	}
+	for _, tt := range tests {
+		base := gf.SelectorBase(gf.TokenPos(Position{Line: tt.line, Column: tt.column}))
+		if base == nil {
+			t.Errorf("SelectorBase(%d, %d) = nil", tt.line, tt.column)
+			continue
+		}
+		if text := gf.NodeText(base); text != tt.text {
+			t.Errorf("NodeText = %q, want %q", text, tt.text)
+		}
+		if got := gf.StartsStatement(base); got != tt.want {
+			t.Errorf("StartsStatement(%s) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"go/build"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var stdPackages struct {
+	once   sync.Once
+	byName map[string][]string // 包名 -> import 路径
+}
+
+// StdPackages 返回标准库中的包，包名 -> import 路径。扫描 GOROOT/src 得到，不包括 cmd、builtin、internal 和 vendor
+func StdPackages() map[string][]string {
+	stdPackages.once.Do(func() {
+		stdPackages.byName = make(map[string][]string)
+		root := filepath.Join(build.Default.GOROOT, "src")
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil || rel == "." {
+				return nil
+			}
+			name := d.Name()
+			// builtin 只是内置标识符的文档，不能导入
+			if rel == "cmd" || rel == "builtin" || name == "internal" || name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if !HasGoFiles(path) {
+				return nil
+			}
+			importPath := filepath.ToSlash(rel)
+			pkg := importPathToAssumedName(importPath)
+			stdPackages.byName[pkg] = append(stdPackages.byName[pkg], importPath)
+			return nil
+		})
+	})
+	return stdPackages.byName
+}
+
+var stdVersion struct {
+	once    sync.Once
+	version string
+}
+
+// StdVersion 本机 go 工具链的版本，比如 go1.22.1，不是编译服务时的版本，go 命令不可用时为空
+func StdVersion() string {
+	stdVersion.once.Do(func() {
+		out, err := exec.Command("go", "env", "GOVERSION").Output()
+		if err == nil {
+			stdVersion.version = strings.TrimSpace(string(out))
+		}
+	})
+	return stdVersion.version
+}
+
+// StdPackageDir 标准库包的源码目录
+func StdPackageDir(path string) string {
+	return filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(path))
+}
+
+// HasGoFiles 目录中是否有测试以外的 go 文件
+func HasGoFiles(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, match := range matches {
+		if !strings.HasSuffix(match, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package file
+
+import (
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// TagSpec 结构体标签中的一个键值对，比如 json:"name,omitempty"
+type TagSpec struct {
+	Key   string
+	Value string
+}
+
+// gorm 标签中常用的设置项，用于发现漏写的分号，比如 type:varchar(1024)index:idx_name
+var gormSettings = []string{
+	"column", "type", "serializer", "size", "primaryKey", "primary_key", "unique", "default",
+	"precision", "scale", "not null", "autoIncrement", "autoCreateTime", "autoUpdateTime",
+	"index", "uniqueIndex", "check", "comment", "embedded", "embeddedPrefix", "foreignKey", "references",
+}
+
+// ParseTag 按照 reflect.StructTag 的约定解析去掉反引号后的标签，格式错误时返回已经解析出的部分和错误
+func ParseTag(tag string) ([]TagSpec, error) {
+	var tags []TagSpec
+	for tag != "" {
+		// 和 reflect.StructTag.Lookup 相同的扫描规则
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 {
+			return tags, fmt.Errorf("bad syntax for struct tag key")
+		}
+		if i+1 >= len(tag) || tag[i] != ':' {
+			return tags, fmt.Errorf("bad syntax for struct tag pair %q", tag)
+		}
+		if tag[i+1] != '"' {
+			return tags, fmt.Errorf("bad syntax for struct tag value of %s", tag[:i])
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return tags, fmt.Errorf("bad syntax for struct tag value of %s", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return tags, fmt.Errorf("bad syntax for struct tag value of %s", key)
+		}
+		tag = tag[i+1:]
+		if tag != "" && tag[0] != ' ' {
+			return tags, fmt.Errorf("struct tag pair %s:%q must be followed by a space", key, value)
+		}
+		tags = append(tags, TagSpec{Key: key, Value: value})
+	}
+	return tags, nil
+}
+
+// fieldTag 返回字段去掉反引号后的标签和解析结果
+func fieldTag(field *ast.Field) (string, []TagSpec) {
+	if field.Tag == nil {
+		return "", nil
+	}
+	raw, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return "", nil
+	}
+	tags, _ := ParseTag(raw)
+	return raw, tags
+}
+
+// LookupTag 返回标签中 key 对应的值
+func LookupTag(tags []TagSpec, key string) (string, bool) {
+	for _, t := range tags {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// FormatTag 将解析后的标签还原为去掉反引号的标签文本
+func FormatTag(tags []TagSpec) string {
+	parts := make([]string, 0, len(tags))
+	for _, t := range tags {
+		parts = append(parts, t.Key+":"+strconv.Quote(t.Value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// CheckTags 检查文件中所有结构体的标签，包括格式错误、gorm 设置项缺少分号和重复的 json 名称
+func (g *GoFile) CheckTags() scanner.ErrorList {
+	var errs scanner.ErrorList
+	report := func(pos token.Pos, format string, args ...interface{}) {
+		errs.Add(g.FileSet.Position(pos), fmt.Sprintf(format, args...))
+	}
+
+	ast.Inspect(g.File, func(n ast.Node) bool {
+		st, ok := n.(*ast.StructType)
+		if !ok || st.Fields == nil {
+			return true
+		}
+		jsonNames := make(map[string]string) // json 名称 -> 字段名
+		for _, field := range st.Fields.List {
+			var tags []TagSpec
+			pos := field.Pos()
+			if field.Tag != nil {
+				pos = field.Tag.Pos()
+				raw, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					continue
+				}
+				tags, err = ParseTag(raw)
+				if err != nil {
+					report(field.Tag.Pos(), "%v", err)
+					continue
+				}
+				if value, ok := LookupTag(tags, "gorm"); ok {
+					for _, msg := range checkGormTag(value) {
+						report(field.Tag.Pos(), "%s", msg)
+					}
+				}
+			}
+
+			value, tagged := LookupTag(tags, "json")
+			name, _, _ := strings.Cut(value, ",")
+			// json:"-" 不参与序列化，没有名称的嵌入字段会展开到外层
+			if name == "-" && !strings.Contains(value, ",") || name == "" && len(field.Names) == 0 {
+				continue
+			}
+			for _, ident := range fieldNames(field) {
+				// 没有标签的未导出字段不参与序列化
+				if !tagged && !token.IsExported(ident) {
+					continue
+				}
+				// 没有名称时 encoding/json 使用字段名
+				jsonName := name
+				if jsonName == "" {
+					jsonName = ident
+				}
+				if other, ok := jsonNames[jsonName]; ok {
+					report(pos, "struct field %s repeats json tag %q also at field %s", ident, jsonName, other)
+					continue
+				}
+				jsonNames[jsonName] = ident
+			}
+		}
+		return true
+	})
+	return errs
+}
+
+// fieldNames 字段名，嵌入字段使用类型名
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) == 0 {
+		if embed, ok := parseEmbed(field.Type); ok {
+			return []string{embed.Name}
+		}
+		return nil
+	}
+	names := make([]string, 0, len(field.Names))
+	for _, ident := range field.Names {
+		names = append(names, ident.Name)
+	}
+	return names
+}
+
+// gorm 设置项的别名，和 gorm 一样按大写比较
+var gormAliases = map[string]string{"PRIMARY_KEY": "PRIMARYKEY"}
+
+// checkGormTag 检查 gorm 标签的设置项，设置项之间用分号分隔。值里面出现另一个设置项说明漏写了分号，
+// 同一个设置项出现多次时 gorm 只使用最后一个，index 和 uniqueIndex 可以出现多次
+func checkGormTag(value string) []string {
+	var msgs []string
+	seen := make(map[string]string) // 大写的设置项 -> 值
+	for _, setting := range strings.Split(value, ";") {
+		key, rest, hasValue := strings.Cut(setting, ":")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if hasValue {
+			if msg := missingGormSemicolon(key, rest); msg != "" {
+				msgs = append(msgs, msg)
+			}
+		}
+
+		upper := strings.ToUpper(key)
+		if alias, ok := gormAliases[upper]; ok {
+			upper = alias
+		}
+		if upper == "INDEX" || upper == "UNIQUEINDEX" {
+			continue
+		}
+		if prev, ok := seen[upper]; ok {
+			if prev == rest {
+				msgs = append(msgs, fmt.Sprintf("gorm tag repeats setting %s", key))
+			} else {
+				msgs = append(msgs, fmt.Sprintf("gorm tag setting %s has conflicting values %q and %q", key, prev, rest))
+			}
+			continue
+		}
+		seen[upper] = rest
+	}
+	return msgs
+}
+
+// missingGormSemicolon 设置项 key 的值 rest 中出现另一个设置项时返回错误信息
+func missingGormSemicolon(key, rest string) string {
+	for _, name := range gormSettings {
+		i := strings.Index(strings.ToLower(rest), strings.ToLower(name)+":")
+		// 设置项名称前面不是字母数字，才是漏写分号而不是值的一部分
+		if i > 0 && isTagWordByte(rest[i-1]) {
+			continue
+		}
+		if i >= 0 {
+			return fmt.Sprintf("gorm tag setting %s:%s is missing ';' before %s", key, rest[:i], rest[i:])
+		}
+	}
+	return ""
+}
+
+func isTagWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
@@ -0,0 +1,110 @@
+package file
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    []TagSpec
+		wantErr bool
+	}{
+		{``, nil, false},
+		{`json:"name"`, []TagSpec{{"json", "name"}}, false},
+		{`json:"name,omitempty" gorm:"type:text"`, []TagSpec{{"json", "name,omitempty"}, {"gorm", "type:text"}}, false},
+		{`  db:"id"  `, []TagSpec{{"db", "id"}}, false},
+		{`json:"a\"b"`, []TagSpec{{"json", `a"b`}}, false},
+		{`json:name`, nil, true},
+		{`json:"name"gorm:"type:text"`, nil, true},
+		{`json:"name`, nil, true},
+		{`:"name"`, nil, true},
+		{`json:"name" db`, []TagSpec{{"json", "name"}}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTag(tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTag(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+		}
+		if len(got) != 0 || len(tt.want) != 0 {
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTag(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	code := "package model\n\n" +
+		"type Index struct {\n" +
+		"	ID       int    `json:\"id\" gorm:\"primary_key\"`\n" +
+		"	KeyWorld string `gorm:\"type:varchar(1024)index:idx_key_world\"`\n" +
+		"	JoinCol  int    `gorm:\"type:int;index:idx_join_col\"`\n" +
+		"	JoinLine int    `gorm:\"type:int;index:a;index:b;primaryKey;primary_key;type:bigint\"`\n" +
+		"	Name     string `json:\"id\"`\n" +
+		"	Ignored  string `json:\"-\"`\n" +
+		"	Other    string `json:\"-\"`\n" +
+		"	Bad      string `json:name`\n" +
+		"	Title    string\n" +
+		"	Label    string `json:\"Title\"`\n" +
+		"	count    int\n" +
+		"	Count    int    `json:\"count\"`\n" +
+		"	Nested   struct {\n" +
+		"		A string `json:\"a\"`\n" +
+		"		B string `json:\"a,omitempty\"`\n" +
+		"	}\n" +
+		"}\n"
+	gf, err := ParseGoCode("model.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line int
+		msg  string
+	}{
+		{5, "missing ';' before index:idx_key_world"},
+		{7, "repeats setting primary_key"},
+		{7, `setting type has conflicting values "int" and "bigint"`},
+		{8, `repeats json tag "id"`},
+		{11, "bad syntax"},
+		{13, `repeats json tag "Title"`},
+		{18, `repeats json tag "a"`},
+	}
+	errs := gf.CheckTags()
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, w := range want {
+		if errs[i].Pos.Line != w.line || !strings.Contains(errs[i].Msg, w.msg) {
+			t.Errorf("error %d: got %d: %s, want %d: ...%s", i, errs[i].Pos.Line, errs[i].Msg, w.line, w.msg)
+		}
+	}
+
+	fields := gf.Types["global"]["Index"].Fields
+	if v, ok := LookupTag(fields[0].Tags, "gorm"); !ok || v != "primary_key" {
+		t.Errorf("ID gorm tag: got %q, %v", v, ok)
+	}
+	if fields[0].Tag != `json:"id" gorm:"primary_key"` {
+		t.Errorf("ID raw tag: got %q", fields[0].Tag)
+	}
+}
+
+func TestFormatTag(t *testing.T) {
+	tests := []string{
+		``,
+		`json:"id"`,
+		`json:"name,omitempty" gorm:"type:varchar(64);default:\"x\""`,
+	}
+	for _, tag := range tests {
+		tags, err := ParseTag(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := FormatTag(tags); got != tag {
+			t.Errorf("FormatTag(ParseTag(%q)) = %q", tag, got)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package file
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// text 文件内容和每一行开始的偏移，按行列查找字节时不需要为每个字节保存位置
+type text struct {
+	data  []byte
+	lines []int // 第 i 行第一个字节的偏移
+}
+
+func newText(data []byte) text {
+	lines := make([]int, 1, bytes.Count(data, []byte{'\n'})+1)
+	for i, b := range data {
+		// 和 go/token 一致，只把 \n 当作换行，\r\n 中的 \r 属于上一行
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return text{data: data, lines: lines}
+}
+
+// lineRange 返回第 line 行的起止偏移，包括行尾的换行符
+func (t text) lineRange(line int) (int, int, bool) {
+	if line < 0 || line >= len(t.lines) {
+		return 0, 0, false
+	}
+	end := len(t.data)
+	if line+1 < len(t.lines) {
+		end = t.lines[line+1]
+	}
+	return t.lines[line], end, true
+}
+
+// offset 将从 0 开始的行列转换为偏移，列按字节计算
+func (t text) offset(line, column int) (int, bool) {
+	start, end, ok := t.lineRange(line)
+	if !ok || column < 0 || start+column >= end {
+		return 0, false
+	}
+	return start + column, true
+}
+
+// byteColumn 将第 line 行按 utf-16 编码单元计算的列转换为字节列，lsp 的位置使用 utf-16 编码单元。
+// 超过行尾时返回行尾，落在代理对中间时返回字符开始的位置
+func (t text) byteColumn(line, character int) int {
+	start, end, ok := t.lineRange(line)
+	if !ok {
+		return character
+	}
+	i, units := start, 0
+	for i < end && t.data[i] != '\n' {
+		r, size := utf8.DecodeRune(t.data[i:end])
+		n := utf16Units(r)
+		if units+n > character {
+			break
+		}
+		units += n
+		i += size
+	}
+	return i - start
+}
+
+// utf16Column 将第 line 行的字节列转换为 utf-16 编码单元的列
+func (t text) utf16Column(line, column int) int {
+	start, end, ok := t.lineRange(line)
+	if !ok {
+		return column
+	}
+	if start+column < end {
+		end = start + column
+	}
+	units := 0
+	for i := start; i < end; {
+		r, size := utf8.DecodeRune(t.data[i:end])
+		units += utf16Units(r)
+		i += size
+	}
+	return units
+}
+
+func utf16Units(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// ByteColumn 将 lsp 第 line 行按 utf-16 计算的列转换为字节列，行从 0 开始
+func (g *GoFile) ByteColumn(line, character int) int {
+	return g.text.byteColumn(line, character)
+}
+
+// UTF16Column 将第 line 行的字节列转换为 lsp 使用的 utf-16 列，行从 0 开始
+func (g *GoFile) UTF16Column(line, column int) int {
+	return g.text.utf16Column(line, column)
+}
+
+// ByteColumn 将 data 第 line 行的 utf-16 列转换为字节列，用于没有解析的文件
+func ByteColumn(data []byte, line, character int) int {
+	return newText(data).byteColumn(line, character)
+}
+
+// UTF16Column 将 data 第 line 行的字节列转换为 utf-16 列，用于没有解析的文件
+func UTF16Column(data []byte, line, column int) int {
+	return newText(data).utf16Column(line, column)
+}
@@ -0,0 +1,157 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCursorText(t *testing.T) {
+	code := "package main\r\n\nfunc main() {\n\tfmt.Println(x)\n}"
+	gf, err := ParseGoCode("main.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bytes := []struct {
+		line, column int
+		want         byte
+		ok           bool
+	}{
+		{0, 0, 'p', true},
+		{0, 12, '\r', true},
+		{0, 13, '\n', true},
+		{0, 14, 0, false},
+		{1, 0, '\n', true},
+		{3, 1, 'f', true},
+		{4, 0, '}', true},
+		{4, 1, 0, false},
+		{5, 0, 0, false},
+	}
+	for _, tt := range bytes {
+		got, err := gf.GetByteByPosition(Position{Line: tt.line, Column: tt.column})
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("GetByteByPosition(%d, %d) = %q, %v, want %q", tt.line, tt.column, got, err, tt.want)
+		}
+	}
+
+	words := []struct {
+		line, column int
+		want         string
+	}{
+		{0, 6, "package"},
+		{0, 8, "m"},
+		{0, 100, "main"},
+		{1, 0, ""},
+		{2, 3, "func"},
+		{3, 4, "fmt."},
+		{3, 13, "fmt.Println(x"},
+		{9, 0, ""},
+	}
+	for _, tt := range words {
+		if got := gf.GetCursorWord(Position{Line: tt.line, Column: tt.column}); got != tt.want {
+			t.Errorf("GetCursorWord(%d, %d) = %q, want %q", tt.line, tt.column, got, tt.want)
+		}
+	}
+}
+
+// benchCode 生成大约 size 字节的源码
+func benchCode(size int) []byte {
+	var sb strings.Builder
+	sb.WriteString("package bench\n\n")
+	for i := 0; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "// F%d 返回参数的和\nfunc F%d(a, b int) int {\n\tc := a + b\n\treturn c\n}\n\n", i, i)
+	}
+	return []byte(sb.String())
+}
+
+// BenchmarkPositionMap 之前每个字节一个 map 条目的存储方式，作为对比
+func BenchmarkPositionMap(b *testing.B) {
+	code := benchCode(100 << 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer := make(map[Position]byte)
+		x, y := 0, 0
+		for _, c := range code {
+			buffer[Position{Filename: "bench.go", Line: y, Column: x}] = c
+			x++
+			if c == '\r' || c == '\n' {
+				y++
+				x = 0
+			}
+		}
+	}
+}
+
+func BenchmarkText(b *testing.B) {
+	code := benchCode(100 << 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newText(code)
+	}
+}
+
+func BenchmarkParseGoCode(b *testing.B) {
+	code := benchCode(100 << 10)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseGoCode("bench.go", code); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCursorWord(b *testing.B) {
+	gf, err := ParseGoCode("bench.go", benchCode(100<<10))
+	if err != nil {
+		b.Fatal(err)
+	}
+	pos := Position{Line: 1000, Column: 12}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gf.GetCursorWord(pos)
+	}
+}
+
+func TestUTF16Column(t *testing.T) {
+	// 第 1 行: 制表符, 中(3 字节 1 单元), 😀(4 字节 2 单元)
+	code := "package main\n\ts := \"中😀\" + x\n"
+	gf, err := ParseGoCode("main.go", []byte(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line, utf16, byteColumn int
+	}{
+		{0, 4, 4},
+		{1, 0, 0},
+		{1, 7, 7},   // 中
+		{1, 8, 10},  // 😀
+		{1, 10, 14}, // 后面的 "
+		{1, 14, 18}, // x
+		{1, 15, 19}, // 行尾
+		{2, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := gf.ByteColumn(tt.line, tt.utf16); got != tt.byteColumn {
+			t.Errorf("ByteColumn(%d, %d) = %d, want %d", tt.line, tt.utf16, got, tt.byteColumn)
+		}
+		if got := gf.UTF16Column(tt.line, tt.byteColumn); got != tt.utf16 {
+			t.Errorf("UTF16Column(%d, %d) = %d, want %d", tt.line, tt.byteColumn, got, tt.utf16)
+		}
+	}
+	// 超过行尾和落在代理对中间
+	if got := gf.ByteColumn(1, 100); got != 19 {
+		t.Errorf("ByteColumn past end = %d, want 19", got)
+	}
+	if got := gf.ByteColumn(1, 9); got != 10 {
+		t.Errorf("ByteColumn inside surrogate pair = %d, want 10", got)
+	}
+	if got := UTF16Column([]byte(code), 1, 10); got != 8 {
+		t.Errorf("UTF16Column = %d, want 8", got)
+	}
+}
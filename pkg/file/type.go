@@ -1,6 +1,10 @@
 package file
 
-import "go/token"
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
 
 type Scope struct {
 	Start token.Pos
@@ -12,11 +16,17 @@ type TypeInfoSpec struct {
 	Scope   Scope
 	Type    string
 	Comment string
+	Tag     string    // 结构体字段去掉反引号的标签，比如 json:"name" gorm:"type:text"
+	Tags    []TagSpec // 解析后的标签，格式错误时只包含错误之前的部分
 }
 
+// BlockSpec 是词法作用域树的一个节点
 type BlockSpec struct {
-	Type  BlockSpecType // 函数块，匿名函数块，普通块
-	Scope Scope
+	Type     BlockSpecType // 文件，函数块，匿名函数块，普通块，if/for/switch/case 等
+	Scope    Scope
+	Parent   *BlockSpec
+	Children []*BlockSpec
+	Decls    map[string]*DeclSpec // 该作用域中声明的标识符
 }
 
 type BlockSpecType int
@@ -25,6 +35,12 @@ const (
 	BlockSpecTypeFunc   BlockSpecType = 0
 	BlockSpecTypeLambda BlockSpecType = 1
 	BlockSpecTypeBlock  BlockSpecType = 2
+	BlockSpecTypeFile   BlockSpecType = 3
+	BlockSpecTypeIf     BlockSpecType = 4
+	BlockSpecTypeFor    BlockSpecType = 5
+	BlockSpecTypeSwitch BlockSpecType = 6
+	BlockSpecTypeSelect BlockSpecType = 7
+	BlockSpecTypeCase   BlockSpecType = 8
 )
 
 func (t BlockSpecType) String() string {
@@ -35,28 +51,127 @@ func (t BlockSpecType) String() string {
 		return "lambda"
 	case BlockSpecTypeBlock:
 		return "block"
+	case BlockSpecTypeFile:
+		return "file"
+	case BlockSpecTypeIf:
+		return "if"
+	case BlockSpecTypeFor:
+		return "for"
+	case BlockSpecTypeSwitch:
+		return "switch"
+	case BlockSpecTypeSelect:
+		return "select"
+	case BlockSpecTypeCase:
+		return "case"
 	}
 	return "unknown"
 }
 
-type FuncSpec struct {
+// DeclSpec 作用域中声明的标识符
+type DeclSpec struct {
 	Name    string
-	Params  []TypeInfoSpec
-	Returns []TypeInfoSpec
-	Scope   Scope
-	Comment string
+	Type    DeclSpecType
+	Pos     token.Pos // 标识符的位置
+	Visible token.Pos // 从该位置开始可见，包级别的声明为 NoPos
+	Node    ast.Node  // 声明所在的节点，比如 *ast.ValueSpec *ast.AssignStmt *ast.Field
+}
+
+type DeclSpecType int
+
+const (
+	DeclSpecTypeVar    DeclSpecType = 0
+	DeclSpecTypeConst  DeclSpecType = 1
+	DeclSpecTypeType   DeclSpecType = 2
+	DeclSpecTypeFunc   DeclSpecType = 3
+	DeclSpecTypeParam  DeclSpecType = 4
+	DeclSpecTypeImport DeclSpecType = 5
+)
+
+type FuncSpec struct {
+	Name       string
+	Recv       *RecvSpec      // 方法的接收者，普通函数为 nil
+	Type       string         // 函数签名，比如 func[T any](a T) error
+	TypeParams []TypeInfoSpec // 类型参数，Type 为约束
+	Params     []TypeInfoSpec
+	Returns    []TypeInfoSpec
+	Scope      Scope
+	Comment    string
+}
+
+// RecvSpec 方法的接收者
+type RecvSpec struct {
+	Name    *string // 接收者名称可以为空，比如 func (*A) Close()
+	Type    string  // 接收者的类型名称，不包括 * 和类型参数
+	Pointer bool    // 是否是指针接收者
 }
 
 type ImportSpec struct {
-	Name  string
+	Name  string // 文件中引用包使用的名称，没有别名时为包名
 	Path  string
+	Alias string // import 时指定的名称，包括 . 和 _，没有指定时为空
 	Scope Scope
 }
 
+// PackageName 包的真实名称，有别名时按照路径推测
+func (i ImportSpec) PackageName() string {
+	if i.Alias == "" {
+		return i.Name
+	}
+	return importPathToAssumedName(i.Path)
+}
+
+// IsDot import . "path"，包的成员可以直接使用
+func (i ImportSpec) IsDot() bool {
+	return i.Alias == "."
+}
+
+// IsBlank import _ "path"，只执行包的 init
+func (i ImportSpec) IsBlank() bool {
+	return i.Alias == "_"
+}
+
 type TypeSpec struct {
-	Name    string
-	Type    string
-	Fields  []TypeInfoSpec
-	Scope   Scope
-	Comment string
+	Name       string
+	Type       string
+	TypeParams []TypeInfoSpec // 类型参数，Type 为约束，比如 T any、N ~int | ~float64
+	Fields     []TypeInfoSpec // struct 的字段，包括嵌入字段，嵌入字段的名称为类型名称
+	Methods    []FuncSpec     // interface 声明的方法
+	Embeds     []EmbedSpec    // struct 嵌入的字段或者 interface 嵌入的接口
+	Scope      Scope
+	Comment    string
+}
+
+// EmbedSpec 嵌入的类型，比如 *ast.File
+type EmbedSpec struct {
+	Type    string // 完整的类型，比如 *ast.File
+	Package string // 类型所在包的名称，当前包为空
+	Name    string // 类型名称，也是嵌入字段的名称，比如 File
+	Pointer bool
+}
+
+// Decl 返回类型的声明，比如 PrefixCache[T any] struct{Sep string; Cache Cache[T]}
+func (t TypeSpec) Decl() string {
+	return t.Name + typeParamsString(t.TypeParams) + " " + t.Type
+}
+
+// typeParamsString 将类型参数转换为 [K comparable, V any]，没有类型参数时返回空
+func typeParamsString(params []TypeInfoSpec) string {
+	if len(params) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, param := range params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		// 相同约束的参数合并，比如 [K, V any]
+		if i+1 < len(params) && params[i+1].Type == param.Type {
+			sb.WriteString(*param.Name)
+			continue
+		}
+		sb.WriteString(*param.Name + " " + param.Type)
+	}
+	sb.WriteByte(']')
+	return sb.String()
 }
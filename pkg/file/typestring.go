@@ -0,0 +1,281 @@
+package file
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// maxInlineFields 行内 struct/interface 最多展示的字段数量，超过的部分用 ... 代替
+const maxInlineFields = 3
+
+// getTypeString 辅助函数，将 ast 类型节点转换为 go 语法的字符串
+func getTypeString(node ast.Node) string {
+	var sb strings.Builder
+	writeType(&sb, node)
+	return sb.String()
+}
+
+func writeType(sb *strings.Builder, node ast.Node) {
+	switch n := node.(type) {
+	case nil:
+	case *ast.Ident:
+		sb.WriteString(n.Name)
+	case *ast.SelectorExpr:
+		writeType(sb, n.X)
+		sb.WriteByte('.')
+		sb.WriteString(n.Sel.Name)
+	case *ast.StarExpr:
+		sb.WriteByte('*')
+		writeType(sb, n.X)
+	case *ast.ParenExpr:
+		sb.WriteByte('(')
+		writeType(sb, n.X)
+		sb.WriteByte(')')
+	case *ast.Ellipsis:
+		sb.WriteString("...")
+		writeType(sb, n.Elt)
+	case *ast.ArrayType:
+		sb.WriteByte('[')
+		if n.Len != nil {
+			writeType(sb, n.Len)
+		}
+		sb.WriteByte(']')
+		writeType(sb, n.Elt)
+	case *ast.MapType:
+		sb.WriteString("map[")
+		writeType(sb, n.Key)
+		sb.WriteByte(']')
+		writeType(sb, n.Value)
+	case *ast.ChanType:
+		writeChanType(sb, n)
+	case *ast.FuncType:
+		sb.WriteString("func")
+		writeSignature(sb, n)
+	case *ast.StructType:
+		writeStructType(sb, n)
+	case *ast.InterfaceType:
+		writeInterfaceType(sb, n)
+	case *ast.IndexExpr:
+		writeType(sb, n.X)
+		sb.WriteByte('[')
+		writeType(sb, n.Index)
+		sb.WriteByte(']')
+	case *ast.IndexListExpr:
+		writeType(sb, n.X)
+		sb.WriteByte('[')
+		for i, index := range n.Indices {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeType(sb, index)
+		}
+		sb.WriteByte(']')
+	case *ast.UnaryExpr:
+		// 约束中的 ~T
+		sb.WriteString(n.Op.String())
+		writeType(sb, n.X)
+	case *ast.BinaryExpr:
+		// 约束中的 A | B，或者数组长度表达式
+		writeType(sb, n.X)
+		sb.WriteByte(' ')
+		sb.WriteString(n.Op.String())
+		sb.WriteByte(' ')
+		writeType(sb, n.Y)
+	case *ast.BasicLit:
+		sb.WriteString(n.Value)
+	case ast.Expr:
+		sb.WriteString(types.ExprString(n))
+	}
+}
+
+func writeChanType(sb *strings.Builder, n *ast.ChanType) {
+	switch n.Dir {
+	case ast.SEND:
+		sb.WriteString("chan<- ")
+	case ast.RECV:
+		sb.WriteString("<-chan ")
+	default:
+		sb.WriteString("chan ")
+	}
+	// chan (<-chan int) 需要括号，否则会被解析成 chan<- chan int
+	if inner, ok := n.Value.(*ast.ChanType); ok && n.Dir == ast.SEND|ast.RECV && inner.Dir == ast.RECV {
+		sb.WriteByte('(')
+		writeType(sb, n.Value)
+		sb.WriteByte(')')
+		return
+	}
+	writeType(sb, n.Value)
+}
+
+// writeSignature 输出 [T any](a int, b string) (int, error)，不包括 func 关键字
+func writeSignature(sb *strings.Builder, n *ast.FuncType) {
+	if n.TypeParams != nil && len(n.TypeParams.List) > 0 {
+		sb.WriteByte('[')
+		writeFieldList(sb, n.TypeParams, ", ")
+		sb.WriteByte(']')
+	}
+	sb.WriteByte('(')
+	writeFieldList(sb, n.Params, ", ")
+	sb.WriteByte(')')
+
+	if n.Results == nil || len(n.Results.List) == 0 {
+		return
+	}
+	sb.WriteByte(' ')
+	if len(n.Results.List) == 1 && len(n.Results.List[0].Names) == 0 {
+		writeType(sb, n.Results.List[0].Type)
+		return
+	}
+	sb.WriteByte('(')
+	writeFieldList(sb, n.Results, ", ")
+	sb.WriteByte(')')
+}
+
+func writeFieldList(sb *strings.Builder, fields *ast.FieldList, sep string) {
+	if fields == nil {
+		return
+	}
+	for i, field := range fields.List {
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		writeField(sb, field)
+	}
+}
+
+func writeField(sb *strings.Builder, field *ast.Field) {
+	for i, name := range field.Names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(name.Name)
+	}
+	if len(field.Names) > 0 {
+		sb.WriteByte(' ')
+	}
+	writeType(sb, field.Type)
+}
+
+func writeStructType(sb *strings.Builder, n *ast.StructType) {
+	sb.WriteString("struct{")
+	if n.Fields != nil {
+		for i, field := range n.Fields.List {
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			if i == maxInlineFields {
+				sb.WriteString("...")
+				break
+			}
+			writeField(sb, field)
+		}
+	}
+	sb.WriteByte('}')
+}
+
+func writeInterfaceType(sb *strings.Builder, n *ast.InterfaceType) {
+	sb.WriteString("interface{")
+	if n.Methods != nil {
+		for i, method := range n.Methods.List {
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			if i == maxInlineFields {
+				sb.WriteString("...")
+				break
+			}
+			// 方法: Name(params) results，嵌入的接口或者类型约束: T
+			if fn, ok := method.Type.(*ast.FuncType); ok && len(method.Names) > 0 {
+				sb.WriteString(method.Names[0].Name)
+				writeSignature(sb, fn)
+				continue
+			}
+			writeType(sb, method.Type)
+		}
+	}
+	sb.WriteByte('}')
+}
+
+// basicZeroValues 预声明类型的零值
+var basicZeroValues = map[string]string{
+	"bool": "false", "string": `""`, "error": "nil", "any": "nil",
+	"int": "0", "int8": "0", "int16": "0", "int32": "0", "int64": "0",
+	"uint": "0", "uint8": "0", "uint16": "0", "uint32": "0", "uint64": "0", "uintptr": "0",
+	"float32": "0", "float64": "0", "complex64": "0", "complex128": "0", "byte": "0", "rune": "0",
+}
+
+// ZeroValue 返回类型字符串对应的零值表达式，lookup 查找类型声明，其他包中的类型名称为 pkg.T。
+// 无法确定底层类型时使用 *new(T)，对任何类型都成立
+func ZeroValue(typ string, lookup func(name string) (*TypeSpec, bool)) string {
+	return zeroValue(typ, lookup, 0)
+}
+
+func zeroValue(typ string, lookup func(name string) (*TypeSpec, bool), depth int) string {
+	if zero, ok := basicZeroValues[typ]; ok {
+		return zero
+	}
+	for _, prefix := range []string{"*", "[]", "map[", "chan ", "chan<- ", "<-chan ", "func", "interface"} {
+		if strings.HasPrefix(typ, prefix) {
+			return "nil"
+		}
+	}
+	if strings.HasPrefix(typ, "[") || strings.HasPrefix(typ, "struct") {
+		return typ + "{}"
+	}
+	// 声明的类型，零值和底层类型相同，struct 和数组使用类型名构造
+	name := typ
+	if pkg, rest, ok := strings.Cut(typ, "."); ok && token.IsIdentifier(pkg) {
+		name = rest
+	}
+	if lookup != nil && depth < maxEmbedDepth && token.IsIdentifier(name) {
+		if spec, ok := lookup(typ); ok && len(spec.TypeParams) == 0 {
+			zero := zeroValue(spec.Type, lookup, depth+1)
+			if strings.HasSuffix(zero, "{}") {
+				return typ + "{}"
+			}
+			if !strings.HasPrefix(zero, "*new(") {
+				return zero
+			}
+		}
+	}
+	return "*new(" + typ + ")"
+}
+
+// QualifyType 给其他包中的类型字符串里没有包名的导出类型加上包名 pkg，比如 *Builder 变为 *strings.Builder
+func QualifyType(typ, pkg string) string {
+	// 可变参数 ...T 不是表达式
+	if rest, ok := strings.CutPrefix(typ, "..."); ok {
+		return "..." + QualifyType(rest, pkg)
+	}
+	expr := parseTypeExpr(typ)
+	if expr == nil || pkg == "" {
+		return typ
+	}
+	changed := false
+	var qualify func(n ast.Node) bool
+	qualify = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			return false
+		case *ast.Field:
+			// 参数和字段的名称不是类型
+			if n.Type != nil {
+				ast.Inspect(n.Type, qualify)
+			}
+			return false
+		case *ast.Ident:
+			if token.IsExported(n.Name) {
+				n.Name = pkg + "." + n.Name
+				changed = true
+			}
+		}
+		return true
+	}
+	ast.Inspect(expr, qualify)
+	if !changed {
+		return typ
+	}
+	return getTypeString(expr)
+}
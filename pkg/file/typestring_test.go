@@ -0,0 +1,124 @@
+package file
+
+import (
+	"bufio"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testdata/typestring.golden 每行是 "源码类型表达式 => 期望输出"，不写期望输出时与源码相同
+func TestGetTypeString(t *testing.T) {
+	f, err := os.Open("testdata/typestring.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		src, want, ok := strings.Cut(line, " => ")
+		if !ok {
+			want = src
+		}
+
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Errorf("parse %q: %v", src, err)
+			continue
+		}
+		if got := getTypeString(expr); got != want {
+			t.Errorf("getTypeString(%q) = %q, want %q", src, got, want)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetTypeStringNil(t *testing.T) {
+	if got := getTypeString(nil); got != "" {
+		t.Errorf("getTypeString(nil) = %q, want empty", got)
+	}
+}
+
+func TestGetTypeStringTypeParams(t *testing.T) {
+	code := `package p
+
+func Map[K comparable, V any, R ~int | ~string](m map[K]V, f func(V) R) []R { return nil }
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "p.go", code, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := f.Decls[0].(*ast.FuncDecl)
+	want := "func[K comparable, V any, R ~int | ~string](m map[K]V, f func(V) R) []R"
+	if got := getTypeString(fn.Type); got != want {
+		t.Errorf("getTypeString = %q, want %q", got, want)
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	gf, err := ParseGoCode("main.go", []byte(`package p
+
+type User struct{ Name string }
+type ID int64
+type Names []string
+type Reader interface{ Read() }
+type Grid [3][3]int
+type Pair[K any] struct{ Key K }
+
+func f() (int, string, *User, User, ID, Names, Reader, Grid, Pair[int], [2]int, T, error) {
+	return
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := gf.EnclosingFunc(gf.File.Decls[len(gf.File.Decls)-1].(*ast.FuncDecl).Body.Rbrace)
+	if fn == nil || fn.Name != "f" {
+		t.Fatalf("EnclosingFunc = %+v", fn)
+	}
+	lookup := func(name string) (*TypeSpec, bool) {
+		spec, ok := gf.Types["global"][name]
+		return &spec, ok
+	}
+	want := []string{"0", `""`, "nil", "User{}", "0", "nil", "nil", "Grid{}", "*new(Pair[int])", "[2]int{}", "*new(T)", "nil"}
+	if len(fn.Returns) != len(want) {
+		t.Fatalf("returns = %+v", fn.Returns)
+	}
+	for i, ret := range fn.Returns {
+		if got := ZeroValue(ret.Type, lookup); got != want[i] {
+			t.Errorf("ZeroValue(%s) = %s, want %s", ret.Type, got, want[i])
+		}
+	}
+}
+
+func TestQualifyType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"Builder", "strings.Builder"},
+		{"*Builder", "*strings.Builder"},
+		{"[]Builder", "[]strings.Builder"},
+		{"map[string]*Reader", "map[string]*strings.Reader"},
+		{"...Builder", "...strings.Builder"},
+		{"func(Name Builder) error", "func(Name strings.Builder) error"},
+		{"io.Reader", "io.Reader"},
+		{"int", "int"},
+		{"any", "any"},
+	}
+	for _, tt := range tests {
+		if got := QualifyType(tt.typ, "strings"); got != tt.want {
+			t.Errorf("QualifyType(%s) = %s, want %s", tt.typ, got, tt.want)
+		}
+	}
+}
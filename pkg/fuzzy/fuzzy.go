@@ -0,0 +1,104 @@
+package fuzzy
+
+import (
+	"unicode"
+)
+
+// 每个匹配字符的得分，匹配在开头、单词边界或者紧接着上一个匹配字符时得分更高
+const (
+	matchScore       = 1.0
+	startBonus       = 2.0 // 第一个字符
+	boundaryBonus    = 1.5 // CamelCase 的大写字母、_ 或者数字之后的字母
+	consecutiveBonus = 1.5 // 紧接着上一个匹配的字符
+	maxCharScore     = matchScore + startBonus
+)
+
+// Score 不区分大小写地将 pattern 作为 candidate 的子序列匹配，返回 (0, 1] 之间的分数，不匹配时返回 false。
+// 完全相同的前缀得分最高，其次是按 CamelCase 或 _ 分隔的单词首字母匹配，最后是普通的子序列。
+// 长度相同的匹配中 candidate 越短分数越高，pattern 为空时匹配任何 candidate
+func Score(pattern, candidate string) (float64, bool) {
+	p, c := []rune(pattern), []rune(candidate)
+	if len(p) == 0 {
+		return 1, true
+	}
+	if len(p) > len(c) {
+		return 0, false
+	}
+
+	// best[j] pattern[:i+1] 最后一个字符匹配在 c[j] 时的最高分，-1 表示不能匹配
+	best := make([]float64, len(c))
+	prev := make([]float64, len(c))
+	for j := range c {
+		prev[j] = -1
+		if equalFold(p[0], c[j]) {
+			prev[j] = charScore(c, j, false)
+		}
+	}
+	for i := 1; i < len(p); i++ {
+		// 在 c[j] 之前结束的最好的匹配
+		before := -1.0
+		for j := range c {
+			best[j] = -1
+			if j > 0 && equalFold(p[i], c[j]) {
+				if prev[j-1] >= 0 {
+					best[j] = prev[j-1] + charScore(c, j, true)
+				}
+				if before >= 0 && before+charScore(c, j, false) > best[j] {
+					best[j] = before + charScore(c, j, false)
+				}
+			}
+			if j > 0 && prev[j-1] > before {
+				before = prev[j-1]
+			}
+		}
+		prev, best = best, prev
+	}
+
+	total := -1.0
+	for _, s := range prev {
+		if s > total {
+			total = s
+		}
+	}
+	if total < 0 {
+		return 0, false
+	}
+	score := total / (maxCharScore * float64(len(p)))
+	if score > 1 {
+		score = 1
+	}
+	// 用未匹配的长度区分得分相同的候选项
+	return score * (0.9 + 0.1*float64(len(p))/float64(len(c))), true
+}
+
+// charScore c[j] 作为匹配字符的得分，consecutive 表示上一个字符匹配在 c[j-1]
+func charScore(c []rune, j int, consecutive bool) float64 {
+	score := matchScore
+	switch {
+	case j == 0:
+		score += startBonus
+	case consecutive:
+		score += consecutiveBonus
+	case isBoundary(c, j):
+		score += boundaryBonus
+	}
+	return score
+}
+
+// isBoundary c[j] 是否是单词的开头: 小写字母或者数字后面的大写字母，_ 或者数字后面的字母
+func isBoundary(c []rune, j int) bool {
+	prev, cur := c[j-1], c[j]
+	switch {
+	case prev == '_' || prev == '.' || prev == '/':
+		return cur != '_'
+	case unicode.IsUpper(cur):
+		return unicode.IsLower(prev) || unicode.IsDigit(prev)
+	case unicode.IsLetter(cur):
+		return unicode.IsDigit(prev)
+	}
+	return false
+}
+
+func equalFold(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
@@ -0,0 +1,61 @@
+package fuzzy
+
+import (
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		candidate string
+		match     bool
+	}{
+		{"", "Println", true},
+		{"pri", "Println", true},
+		{"PL", "Println", true},
+		{"rf", "ReadFile", true},
+		{"rdfl", "ReadFile", true},
+		{"nf", "new_file", true},
+		{"fr", "ReadFile", false},
+		{"printlnx", "Println", false},
+		{"é", "Éclair", true},
+	}
+	for _, tt := range tests {
+		score, ok := Score(tt.pattern, tt.candidate)
+		if ok != tt.match {
+			t.Errorf("Score(%q, %q) match = %v, want %v", tt.pattern, tt.candidate, ok, tt.match)
+			continue
+		}
+		if ok && (score <= 0 || score > 1) {
+			t.Errorf("Score(%q, %q) = %v, want (0, 1]", tt.pattern, tt.candidate, score)
+		}
+	}
+}
+
+func TestScoreOrder(t *testing.T) {
+	// 每组中前面的候选项得分更高
+	tests := []struct {
+		pattern    string
+		candidates []string
+	}{
+		{"rf", []string{"ReadFile", "reframe", "xrxf"}},
+		{"pri", []string{"print", "Println", "sPrint", "pxrxi"}},
+		{"err", []string{"err", "errors", "isErr"}},
+		{"nf", []string{"new_file", "nofile"}},
+		{"ctx", []string{"ctx", "context"}},
+	}
+	for _, tt := range tests {
+		last := 2.0
+		for _, candidate := range tt.candidates {
+			score, ok := Score(tt.pattern, candidate)
+			if !ok {
+				t.Errorf("Score(%q, %q) does not match", tt.pattern, candidate)
+				break
+			}
+			if score >= last {
+				t.Errorf("Score(%q, %q) = %v, want less than previous %v", tt.pattern, candidate, score, last)
+			}
+			last = score
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package semantic
+
+import (
+	"fmt"
+	"github.com/denstiny/golang-language-server/pkg/file"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 估算类型检查结果占用的内存，源码字节和 types.Info 中每个条目的平均开销。
+// CostPerSourceByte 也用于估算只解析了语法的包
+const (
+	CostPerSourceByte = 48
+	costPerInfoEntry  = 160
+)
+
+// Package 一个完成类型检查的包
+type Package struct {
+	Dir   string
+	Fset  *token.FileSet
+	Files map[string]*ast.File // 文件路径 -> ast
+	Types *types.Package
+	Info  *types.Info
+	Errs  []error // 类型错误，有错误时结果仍然可用
+	size  int64
+	deps  map[string]bool // 直接和间接依赖的包的目录，其中任意一个修改后结果失效
+}
+
+// Size 估算的内存占用
+func (p *Package) Size() int64 {
+	return p.size
+}
+
+// Checker 使用 go/types 对打开的包做类型检查，结果保存在有内存上限的 LRU 中
+type Checker struct {
+	mu       sync.Mutex
+	packages *LRU[string, *Package] // 包目录 -> 类型检查结果
+	exports  map[exportKey]*export  // 依赖包的 export data
+	build    file.BuildConfig       // 构建约束不成立的文件不参与类型检查
+	// ReadFile 读取包中文件的源码，默认读取磁盘。编辑器中修改过的文件要使用编辑器中的内容，
+	// 否则客户端的位置对应不到 ast 上
+	ReadFile func(filename string) ([]byte, error)
+}
+
+// exportKey 同一个 import 路径在不同的 module 中可能是不同的版本或者被 replace 的目录
+type exportKey struct {
+	module string // go.mod 所在的目录，不在 module 中时为包的目录
+	path   string
+}
+
+// export 依赖包的 export data 文件
+type export struct {
+	file string
+	deps map[string]bool // 包和它依赖的包的目录
+}
+
+func NewChecker(budget int64) *Checker {
+	return &Checker{
+		packages: NewLRU[string](budget, (*Package).Size),
+		exports:  make(map[exportKey]*export),
+		build:    file.DefaultBuildConfig(),
+		ReadFile: os.ReadFile,
+	}
+}
+
+// SetBuild 修改目标平台和构建标签，已经检查过的包全部失效
+func (c *Checker) SetBuild(build file.BuildConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.build = build
+	c.packages.Clear()
+	c.exports = make(map[exportKey]*export)
+}
+
+// Get 返回已经完成类型检查的包，不会触发类型检查
+func (c *Checker) Get(dir string) (*Package, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.packages.Get(dir)
+}
+
+// Load 返回 dir 的类型检查结果，没有缓存时进行类型检查
+func (c *Checker) Load(dir string) (*Package, error) {
+	if pkg, ok := c.Get(dir); ok {
+		return pkg, nil
+	}
+	pkg, err := c.check(dir)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.packages.Add(dir, pkg)
+	c.mu.Unlock()
+	return pkg, nil
+}
+
+// Invalidate 文件修改后删除包的类型检查结果，依赖这个包的包和 export data 也一起失效
+func (c *Checker) Invalidate(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.packages.RemoveFunc(func(_ string, pkg *Package) bool {
+		return pkg.Dir == dir || pkg.deps[dir]
+	})
+	for key, e := range c.exports {
+		if e.deps[dir] {
+			delete(c.exports, key)
+		}
+	}
+}
+
+// Used 当前估算的内存占用
+func (c *Checker) Used() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.packages.Used()
+}
+
+func (c *Checker) check(dir string) (*Package, error) {
+	fset := token.NewFileSet()
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &Package{
+		Dir:   dir,
+		Fset:  fset,
+		Files: make(map[string]*ast.File),
+		deps:  make(map[string]bool),
+		Info: &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		},
+	}
+
+	var files []*ast.File
+	var name string
+	for _, filename := range filenames {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		src, err := c.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.AllErrors)
+		if f == nil {
+			return nil, err
+		}
+		// 其他平台的文件，或者 //go:build ignore 的工具文件
+		if !c.buildConfig().Match(file.FileConstraint(filename, f)) {
+			continue
+		}
+		// 同一个目录下的其他包，比如 package main 的工具文件
+		if name != "" && f.Name.Name != name {
+			continue
+		}
+		name = f.Name.Name
+		files = append(files, f)
+		pkg.Files[filename] = f
+		pkg.size += int64(len(src)) * CostPerSourceByte
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no go files in %s", dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+			e, err := c.lookupExport(dir, path)
+			if err != nil {
+				return nil, err
+			}
+			for dep := range e.deps {
+				pkg.deps[dep] = true
+			}
+			return os.Open(e.file)
+		}),
+		Error: func(err error) {
+			pkg.Errs = append(pkg.Errs, err)
+		},
+	}
+	// 有类型错误时仍然返回部分结果
+	pkg.Types, _ = conf.Check(name, fset, files, pkg.Info)
+
+	entries := len(pkg.Info.Types) + len(pkg.Info.Defs) + len(pkg.Info.Uses) + len(pkg.Info.Selections)
+	pkg.size += int64(entries) * costPerInfoEntry
+	return pkg, nil
+}
+
+func (c *Checker) buildConfig() file.BuildConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.build
+}
+
+// lookupExport 通过 go list -export 查找依赖包的 export data，不需要加载依赖的源码
+func (c *Checker) lookupExport(dir, path string) (*export, error) {
+	key := exportKey{module: moduleRoot(dir), path: path}
+	c.mu.Lock()
+	e, ok := c.exports[key]
+	c.mu.Unlock()
+	if ok {
+		return e, nil
+	}
+
+	// -deps 同时列出间接依赖的目录，目标包在最后一行
+	build := c.buildConfig()
+	cmd := exec.Command("go", "list", "-deps", "-export", "-tags", strings.Join(build.Tags, ","), "-f", "{{.Dir}}\t{{.Export}}", path)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS="+build.GOOS, "GOARCH="+build.GOARCH)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -export %s: %v", path, err)
+	}
+	e = &export{deps: make(map[string]bool)}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		depDir, exportFile, _ := strings.Cut(line, "\t")
+		e.deps[depDir] = true
+		e.file = exportFile
+	}
+	if e.file == "" {
+		return nil, fmt.Errorf("no export data for %s", path)
+	}
+	c.mu.Lock()
+	c.exports[key] = e
+	c.mu.Unlock()
+	return e, nil
+}
+
+// moduleRoot 返回 dir 所在 module 的根目录，不在 module 中时返回 dir
+func moduleRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
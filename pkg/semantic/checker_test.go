@@ -0,0 +1,195 @@
+package semantic
+
+import (
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const checkerCode = `package p
+
+import "strings"
+
+type Index struct{ Name string }
+
+func Upper(i *Index) string {
+	name := strings.ToUpper(i.Name)
+	return name
+}
+`
+
+func writePackage(t *testing.T, code string) string {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/p\n\ngo 1.22\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "p.go"), []byte(code), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCheckerLoad(t *testing.T) {
+	dir := writePackage(t, checkerCode)
+	c := NewChecker(64 << 20)
+	pkg, err := c.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Errs) != 0 {
+		t.Fatalf("type errors: %v", pkg.Errs)
+	}
+
+	filename := filepath.Join(dir, "p.go")
+	posOf := func(s string) token.Pos {
+		return pkg.Fset.File(pkg.Files[filename].Pos()).Pos(strings.Index(checkerCode, s))
+	}
+	obj := pkg.ObjectAt(filename, posOf("name :="))
+	if obj == nil || obj.Type().String() != "string" {
+		t.Errorf("name = %v, want string var", obj)
+	}
+
+	obj = pkg.ObjectAt(filename, posOf("ToUpper"))
+	if obj == nil || obj.Pkg().Path() != "strings" {
+		t.Errorf("ToUpper = %v, want strings.ToUpper", obj)
+	}
+	if got := types.ObjectString(obj, pkg.Qualifier); got != "func strings.ToUpper(s string) string" {
+		t.Errorf("ObjectString = %q", got)
+	}
+
+	// 第 7 行 "	name := ..." 的第 1 列
+	if pos := pkg.PosAt(filename, 7, 1); pos != posOf("name :=") {
+		t.Errorf("PosAt = %v, want %v", pos, posOf("name :="))
+	}
+
+	if _, ok := c.Get(dir); !ok {
+		t.Errorf("package should be cached")
+	}
+	c.Invalidate(dir)
+	if _, ok := c.Get(dir); ok || c.Used() != 0 {
+		t.Errorf("package should be invalidated")
+	}
+}
+
+func TestCheckerReadFile(t *testing.T) {
+	dir := writePackage(t, checkerCode)
+	filename := filepath.Join(dir, "p.go")
+	// 编辑器中还没有保存的修改，name 所在的行向下移动了两行
+	buffer := strings.Replace(checkerCode, "func Upper", "var Extra = 1\n\nfunc Upper", 1)
+	c := NewChecker(64 << 20)
+	c.ReadFile = func(name string) ([]byte, error) {
+		if name == filename {
+			return []byte(buffer), nil
+		}
+		return os.ReadFile(name)
+	}
+	pkg, err := c.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Types.Scope().Lookup("Extra") == nil {
+		t.Errorf("Extra from the unsaved buffer is not declared")
+	}
+	// 第 9 行 "	name := ..." 的第 1 列，按磁盘上的内容是 return 语句
+	obj := pkg.ObjectAt(filename, pkg.PosAt(filename, 9, 1))
+	if obj == nil || obj.Name() != "name" {
+		t.Errorf("object at buffer position = %v, want name", obj)
+	}
+}
+
+func TestCheckerBudget(t *testing.T) {
+	a := writePackage(t, checkerCode)
+	b := writePackage(t, checkerCode)
+
+	// 上限只能放下一个包
+	c := NewChecker(1)
+	if _, err := c.Load(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Load(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(a); ok {
+		t.Errorf("least recently used package should be evicted")
+	}
+	if _, ok := c.Get(b); !ok {
+		t.Errorf("latest package should be kept")
+	}
+}
+
+// writeModule 写入 module example.com/m，files 是相对路径 -> 源码
+func writeModule(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.22\n"
+	for name, code := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const importerCode = `package m
+
+import "example.com/m/dep"
+
+var V = dep.Value
+`
+
+func TestCheckerExportsPerModule(t *testing.T) {
+	// 两个 module 的 import 路径相同，dep.Value 的类型不同
+	a := writeModule(t, map[string]string{"m.go": importerCode, "dep/dep.go": "package dep\n\nvar Value int\n"})
+	b := writeModule(t, map[string]string{"m.go": importerCode, "dep/dep.go": "package dep\n\nvar Value string\n"})
+
+	c := NewChecker(64 << 20)
+	for dir, want := range map[string]string{a: "int", b: "string"} {
+		pkg, err := c.Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pkg.Types.Scope().Lookup("V").Type().String(); got != want || len(pkg.Errs) != 0 {
+			t.Errorf("V in %s = %s %v, want %s", dir, got, pkg.Errs, want)
+		}
+	}
+}
+
+func TestCheckerInvalidateDependents(t *testing.T) {
+	dir := writeModule(t, map[string]string{"m.go": importerCode, "dep/dep.go": "package dep\n\nvar Value int\n"})
+	depDir := filepath.Join(dir, "dep")
+
+	c := NewChecker(64 << 20)
+	if _, err := c.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	// 修改依赖的包，使用它的包也要重新检查
+	err := os.WriteFile(filepath.Join(depDir, "dep.go"), []byte("package dep\n\nvar Value bool\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Invalidate(depDir)
+	if _, ok := c.Get(dir); ok {
+		t.Fatalf("package importing %s should be invalidated", depDir)
+	}
+	pkg, err := c.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pkg.Types.Scope().Lookup("V").Type().String(); got != "bool" {
+		t.Errorf("V after editing dep = %s, want bool", got)
+	}
+
+	// 不相关的包修改后结果保留
+	c.Invalidate(t.TempDir())
+	if _, ok := c.Get(dir); !ok {
+		t.Errorf("unrelated invalidation dropped the package")
+	}
+}
@@ -0,0 +1,91 @@
+package semantic
+
+import (
+	"container/list"
+)
+
+// LRU 有内存上限的缓存，超过上限时淘汰最久没有使用的条目，不是并发安全的
+type LRU[K comparable, V any] struct {
+	budget int64 // 内存上限，字节
+	used   int64
+	list   *list.List          // 最近使用的条目在前面
+	items  map[K]*list.Element // key -> list 节点
+	size   func(V) int64       // 估算条目的内存占用
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+func NewLRU[K comparable, V any](budget int64, size func(V) int64) *LRU[K, V] {
+	return &LRU[K, V]{
+		budget: budget,
+		list:   list.New(),
+		items:  make(map[K]*list.Element),
+		size:   size,
+	}
+}
+
+// Get 返回 key 对应的条目，并标记为最近使用
+func (l *LRU[K, V]) Get(key K) (V, bool) {
+	elem, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	l.list.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+// Add 加入或者替换 key 对应的条目，超过上限时淘汰最久没有使用的条目，至少保留刚加入的条目
+func (l *LRU[K, V]) Add(key K, value V) {
+	if elem, ok := l.items[key]; ok {
+		l.remove(elem)
+	}
+	entry := &lruEntry[K, V]{key: key, value: value, size: l.size(value)}
+	l.items[key] = l.list.PushFront(entry)
+	l.used += entry.size
+
+	for l.used > l.budget && l.list.Len() > 1 {
+		l.remove(l.list.Back())
+	}
+}
+
+// Remove 删除 key 对应的条目
+func (l *LRU[K, V]) Remove(key K) {
+	if elem, ok := l.items[key]; ok {
+		l.remove(elem)
+	}
+}
+
+// RemoveFunc 删除 match 返回 true 的条目
+func (l *LRU[K, V]) RemoveFunc(match func(key K, value V) bool) {
+	for elem := l.list.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*lruEntry[K, V])
+		if match(entry.key, entry.value) {
+			l.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// Clear 删除全部条目
+func (l *LRU[K, V]) Clear() {
+	l.list.Init()
+	l.items = make(map[K]*list.Element)
+	l.used = 0
+}
+
+// Used 当前估算的内存占用
+func (l *LRU[K, V]) Used() int64 {
+	return l.used
+}
+
+func (l *LRU[K, V]) remove(elem *list.Element) {
+	entry := l.list.Remove(elem).(*lruEntry[K, V])
+	delete(l.items, entry.key)
+	l.used -= entry.size
+}
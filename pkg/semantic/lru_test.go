@@ -0,0 +1,47 @@
+package semantic
+
+import (
+	"testing"
+)
+
+func TestLRU(t *testing.T) {
+	// 每个条目的大小是字符串的长度，上限 10
+	l := NewLRU[string](10, func(v string) int64 { return int64(len(v)) })
+	l.Add("a", "aaaa")
+	l.Add("b", "bbbb")
+	l.Get("a")
+	l.Add("c", "cccc") // 超过上限，淘汰最久没有使用的 b
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := l.Get(tt.key); ok != tt.want {
+			t.Errorf("Get(%s) = %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+	if l.Used() != 8 {
+		t.Errorf("Used = %d, want 8", l.Used())
+	}
+
+	// 单个条目超过上限时仍然保留
+	l.Add("big", "0123456789abc")
+	if v, ok := l.Get("big"); !ok || v != "0123456789abc" || l.Used() != 13 {
+		t.Errorf("big entry = %q, %v, used %d", v, ok, l.Used())
+	}
+
+	l.Add("d", "dd")
+	l.RemoveFunc(func(key, _ string) bool { return key == "d" })
+	if _, ok := l.Get("d"); ok {
+		t.Errorf("d should be removed")
+	}
+	l.Clear()
+	if _, ok := l.Get("big"); ok || l.Used() != 0 {
+		t.Errorf("Clear left entries, used %d", l.Used())
+	}
+}
@@ -0,0 +1,132 @@
+package semantic
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PosAt 将 filename 中从 0 开始的行列位置转换为 token.Pos
+func (p *Package) PosAt(filename string, line, column int) token.Pos {
+	f, ok := p.Files[filename]
+	if !ok {
+		return token.NoPos
+	}
+	tf := p.Fset.File(f.Pos())
+	if tf == nil || line < 0 || line >= tf.LineCount() {
+		return token.NoPos
+	}
+	offset := tf.Offset(tf.LineStart(line+1)) + column
+	if offset > tf.Size() {
+		offset = tf.Size()
+	}
+	return tf.Pos(offset)
+}
+
+// IdentAt 返回 filename 中 pos 处的标识符
+func (p *Package) IdentAt(filename string, pos token.Pos) *ast.Ident {
+	f, ok := p.Files[filename]
+	if !ok || pos == token.NoPos {
+		return nil
+	}
+
+	var ident *ast.Ident
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil || ident != nil {
+			return false
+		}
+		if pos < n.Pos() || pos > n.End() {
+			return false
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			ident = id
+			return false
+		}
+		return true
+	})
+	return ident
+}
+
+// ObjectAt 返回 filename 中 pos 处的标识符引用或者定义的对象
+func (p *Package) ObjectAt(filename string, pos token.Pos) types.Object {
+	ident := p.IdentAt(filename, pos)
+	if ident == nil {
+		return nil
+	}
+	return p.Info.ObjectOf(ident)
+}
+
+// TypeOf 返回表达式的类型
+func (p *Package) TypeOf(expr ast.Expr) types.Type {
+	return p.Info.TypeOf(expr)
+}
+
+// Position 返回对象定义的位置，其他包中的对象位置来自 export data
+func (p *Package) Position(obj types.Object) token.Position {
+	position := p.Fset.Position(obj.Pos())
+	// export data 中标准库的文件路径以 $GOROOT 开头
+	if rest, ok := strings.CutPrefix(position.Filename, "$GOROOT"); ok {
+		position.Filename = filepath.Join(runtime.GOROOT(), rest)
+	}
+	return position
+}
+
+// Qualifier 使用包名限定其他包的类型，当前包的类型不加限定
+func (p *Package) Qualifier(other *types.Package) string {
+	if other == p.Types {
+		return ""
+	}
+	return other.Name()
+}
+
+// FieldTag 返回结构体字段的标签，在包中出现过的结构体类型(包括嵌入的结构体)中查找
+func (p *Package) FieldTag(field *types.Var) string {
+	if !field.IsField() {
+		return ""
+	}
+	seen := make(map[*types.Struct]bool)
+	var find func(t types.Type, depth int) (string, bool)
+	find = func(t types.Type, depth int) (string, bool) {
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok || seen[st] || depth > 8 {
+			return "", false
+		}
+		seen[st] = true
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if f == field {
+				return st.Tag(i), true
+			}
+			if f.Embedded() {
+				if tag, ok := find(f.Type(), depth+1); ok {
+					return tag, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	for _, tv := range p.Info.Types {
+		if tv.Type == nil {
+			continue
+		}
+		if tag, ok := find(tv.Type, 0); ok {
+			return tag
+		}
+	}
+	for _, obj := range p.Info.Defs {
+		if obj == nil {
+			continue
+		}
+		if tag, ok := find(obj.Type(), 0); ok {
+			return tag
+		}
+	}
+	return ""
+}
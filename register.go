@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"github.com/denstiny/golang-language-server/biz/dal/document"
+	"github.com/denstiny/golang-language-server/biz/dal/typecheck"
 	"github.com/denstiny/golang-language-server/biz/handle/completion"
+	"github.com/denstiny/golang-language-server/biz/handle/definition"
+	"github.com/denstiny/golang-language-server/biz/handle/diagnostic"
+	"github.com/denstiny/golang-language-server/biz/handle/hover"
 	"github.com/denstiny/golang-language-server/biz/handle/initialize"
 	"github.com/denstiny/golang-language-server/biz/handle/initialized"
 	"github.com/denstiny/golang-language-server/pkg/engine"
+	"github.com/denstiny/golang-language-server/pkg/file"
 	"github.com/sourcegraph/jsonrpc2"
 	"pkg.nimblebun.works/go-lsp"
 )
@@ -28,15 +34,82 @@ func RpcHandles() map[string]engine.RouteFunc {
 			return nil, nil
 		},
 		"textDocument/didSave": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.DidSaveTextDocumentParams
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			typecheck.Invalidate(file.URIToPath(string(param.TextDocument.URI)))
+			return nil, diagnostic.CheckFile(ctx, param.TextDocument.URI)
+		},
+		"textDocument/didOpen": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.DidOpenTextDocumentParams
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			document.Set(string(param.TextDocument.URI), []byte(param.TextDocument.Text))
+			typecheck.Invalidate(file.URIToPath(string(param.TextDocument.URI)))
+			return nil, diagnostic.Check(ctx, param.TextDocument.URI, []byte(param.TextDocument.Text))
+		},
+		"textDocument/didChange": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.DidChangeTextDocumentParams
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			// 全量同步，最后一次修改就是完整的文件内容
+			if len(param.ContentChanges) == 0 {
+				return nil, nil
+			}
+			text := param.ContentChanges[len(param.ContentChanges)-1].Text
+			document.Set(string(param.TextDocument.URI), []byte(text))
+			// 类型检查使用编辑器中的内容，修改后位置都可能变化
+			typecheck.Invalidate(file.URIToPath(string(param.TextDocument.URI)))
+			return nil, diagnostic.Check(ctx, param.TextDocument.URI, []byte(text))
+		},
+		"textDocument/didClose": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.DidCloseTextDocumentParams
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			document.Delete(string(param.TextDocument.URI))
+			// 没有保存就关闭时，类型检查要回到磁盘上的内容
+			typecheck.Invalidate(file.URIToPath(string(param.TextDocument.URI)))
 			return nil, nil
 		},
+		"textDocument/hover": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.HoverParams
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			return hover.Handle(ctx, c, &param)
+		},
+		"textDocument/definition": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.DefinitionParams
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			return definition.Handle(ctx, c, &param)
+		},
 		"textDocument/completion": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
 			var param lsp.CompletionParams
 			err := json.Unmarshal(*req.Params, &param)
 			if err != nil {
 				return nil, err
 			}
-			return completion.Handle(ctx, &param)
+			return completion.Handle(ctx, c, &param)
+		},
+		"completionItem/resolve": func(ctx context.Context, c *engine.LspService, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (interface{}, error) {
+			var param lsp.CompletionItem
+			err := json.Unmarshal(*req.Params, &param)
+			if err != nil {
+				return nil, err
+			}
+			return completion.Resolve(ctx, c, &param)
 		},
 	}
 }